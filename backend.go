@@ -0,0 +1,41 @@
+package user32util
+
+// Backend sends synthesized input to the operating system.
+//
+// Higher-level input helpers (e.g., Sequence) send input through
+// a Backend, which allows them to be used with an InputSession or
+// to be exercised without touching the user's desktop.
+type Backend interface {
+	// SendInputs sends several inputs. Refer to SendInputs
+	// for more information.
+	SendInputs(inputs []Input) error
+
+	// SetCursorPos moves the mouse cursor to the specified
+	// screen coordinates.
+	SetCursorPos(x int32, y int32) error
+}
+
+// NewUser32Backend returns a Backend that sends input using
+// the provided user32 DLL.
+func NewUser32Backend(user32 *User32DLL) Backend {
+	return &user32Backend{
+		user32: user32,
+	}
+}
+
+type user32Backend struct {
+	user32 *User32DLL
+}
+
+func (o *user32Backend) SendInputs(inputs []Input) error {
+	return SendInputs(inputs, o.user32)
+}
+
+func (o *user32Backend) SetCursorPos(x int32, y int32) error {
+	ok, err := SetCursorPos(x, y, o.user32)
+	if ok {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,109 @@
+package user32util
+
+import (
+	"sync"
+)
+
+// fakeBackend is a Backend that records the inputs and cursor movements
+// that it receives instead of sending them to the operating system.
+type fakeBackend struct {
+	mu      sync.Mutex
+	batches [][]Input
+	moves   []Point
+
+	// sendErr, if non-nil, is returned by SendInputs once failSend
+	// batches have been recorded.
+	sendErr  error
+	failSend int
+
+	// moveErr, if non-nil, is returned by SetCursorPos.
+	moveErr error
+
+	// onSend, if non-nil, is called after each batch is recorded.
+	onSend func(batch []Input)
+}
+
+func (o *fakeBackend) SendInputs(inputs []Input) error {
+	o.mu.Lock()
+	batch := append([]Input(nil), inputs...)
+	o.batches = append(o.batches, batch)
+	fail := o.sendErr != nil && len(o.batches) > o.failSend
+	onSend := o.onSend
+	o.mu.Unlock()
+
+	if onSend != nil {
+		onSend(batch)
+	}
+
+	if fail {
+		return o.sendErr
+	}
+
+	return nil
+}
+
+func (o *fakeBackend) SetCursorPos(x int32, y int32) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.moveErr != nil {
+		return o.moveErr
+	}
+
+	o.moves = append(o.moves, Point{X: x, Y: y})
+
+	return nil
+}
+
+// inputs returns every recorded input in the order it was sent.
+func (o *fakeBackend) inputs() []Input {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var inputs []Input
+	for _, batch := range o.batches {
+		inputs = append(inputs, batch...)
+	}
+
+	return inputs
+}
+
+// lastBatch returns the most recently recorded batch.
+func (o *fakeBackend) lastBatch() []Input {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.batches) == 0 {
+		return nil
+	}
+
+	return o.batches[len(o.batches)-1]
+}
+
+// inputValues converts inputs into their KeybdInput, MouseInput and
+// HardwareInput values so that they can be compared without comparing
+// the unused bytes of the Input union.
+func inputValues(inputs []Input) []interface{} {
+	values := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		if kb, ok := input.KeybdInput(); ok {
+			values[i] = kb
+		} else if mouse, ok := input.MouseInput(); ok {
+			values[i] = mouse
+		} else if hw, ok := input.HardwareInput(); ok {
+			values[i] = hw
+		}
+	}
+
+	return values
+}
+
+// batchValues calls inputValues for each batch.
+func batchValues(batches [][]Input) [][]interface{} {
+	values := make([][]interface{}, len(batches))
+	for i, batch := range batches {
+		values[i] = inputValues(batch)
+	}
+
+	return values
+}
@@ -0,0 +1,332 @@
+package user32util
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// GetSystemMetrics nIndex values.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getsystemmetrics
+const (
+	SMCxScreen        int32 = 0
+	SMCyScreen        int32 = 1
+	SMCxDoubleClk     int32 = 36
+	SMCyDoubleClk     int32 = 37
+	SMCxDrag          int32 = 68
+	SMCyDrag          int32 = 69
+	SMXVirtualScreen  int32 = 76
+	SMYVirtualScreen  int32 = 77
+	SMCxVirtualScreen int32 = 78
+	SMCyVirtualScreen int32 = 79
+)
+
+// dragSettleDelay is the amount of time that DragTo waits after pressing
+// the mouse button and before moving the cursor, giving the application
+// a chance to register the press.
+const dragSettleDelay = 50 * time.Millisecond
+
+// GetSystemMetrics wraps the 'GetSystemMetrics()' system call, returning
+// the specified system metric or configuration setting. Zero is returned
+// if the call fails, including when the procedure is unavailable (refer to
+// User32DLL.Capabilities).
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getsystemmetrics
+func GetSystemMetrics(index int32, user32 *User32DLL) int32 {
+	ret, _, _ := user32.proc(procGetSystemMetrics).Call(uintptr(index))
+	return int32(ret)
+}
+
+// GetDoubleClickTime returns the maximum amount of time that may occur
+// between the first and second clicks of a double-click. Zero is returned
+// if the procedure is unavailable (refer to User32DLL.Capabilities).
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getdoubleclicktime
+func GetDoubleClickTime(user32 *User32DLL) time.Duration {
+	ret, _, _ := user32.proc(procGetDoubleClickTime).Call()
+	return time.Duration(ret) * time.Millisecond
+}
+
+// ClickMetrics contains the system settings that determine how clicks
+// and drags are recognized.
+type ClickMetrics struct {
+	// DoubleClickTime is the maximum amount of time between the clicks
+	// of a double-click.
+	DoubleClickTime time.Duration
+
+	// DoubleClickWidth and DoubleClickHeight are the dimensions of the
+	// rectangle that the second click of a double-click must occur in.
+	DoubleClickWidth  int32
+	DoubleClickHeight int32
+
+	// DragWidth and DragHeight are the dimensions of the rectangle that
+	// the cursor must leave before a drag operation begins.
+	DragWidth  int32
+	DragHeight int32
+}
+
+// DefaultClickMetrics returns the Windows default click metrics.
+func DefaultClickMetrics() ClickMetrics {
+	return ClickMetrics{
+		DoubleClickTime:   500 * time.Millisecond,
+		DoubleClickWidth:  4,
+		DoubleClickHeight: 4,
+		DragWidth:         4,
+		DragHeight:        4,
+	}
+}
+
+// GetClickMetrics returns the current system click metrics.
+func GetClickMetrics(user32 *User32DLL) ClickMetrics {
+	return ClickMetrics{
+		DoubleClickTime:   GetDoubleClickTime(user32),
+		DoubleClickWidth:  GetSystemMetrics(SMCxDoubleClk, user32),
+		DoubleClickHeight: GetSystemMetrics(SMCyDoubleClk, user32),
+		DragWidth:         GetSystemMetrics(SMCxDrag, user32),
+		DragHeight:        GetSystemMetrics(SMCyDrag, user32),
+	}
+}
+
+// DoubleClickRect returns the rectangle, centered on p, that the next
+// click must occur in to be part of the same multi-click as a click at p.
+func (o ClickMetrics) DoubleClickRect(p Point) Rect {
+	return centeredRect(p, o.DoubleClickWidth, o.DoubleClickHeight)
+}
+
+// DragRect returns the rectangle, centered on p, that the cursor must
+// leave after a button is pressed at p for a drag operation to begin.
+func (o ClickMetrics) DragRect(p Point) Rect {
+	return centeredRect(p, o.DragWidth, o.DragHeight)
+}
+
+// dragExitPoint returns the first point on the line from p toward target
+// that is outside of the drag rectangle centered on p. If target is p,
+// the line runs to the right.
+func (o ClickMetrics) dragExitPoint(p Point, target Point) Point {
+	dx := float64(target.X - p.X)
+	dy := float64(target.Y - p.Y)
+	if dx == 0 && dy == 0 {
+		dx = 1
+	}
+
+	// Advance one pixel at a time along the dominant axis.
+	steps := math.Max(math.Abs(dx), math.Abs(dy))
+	rect := o.DragRect(p)
+
+	for i := 1.0; ; i++ {
+		exit := Point{
+			X: p.X + int32(math.Round(dx*i/steps)),
+			Y: p.Y + int32(math.Round(dy*i/steps)),
+		}
+
+		if !rect.Contains(exit) {
+			return exit
+		}
+	}
+}
+
+// centeredRect returns a rectangle of the specified size centered on p.
+// The rectangle always contains p.
+func centeredRect(p Point, width int32, height int32) Rect {
+	if width < 1 {
+		width = 1
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	left := p.X - width/2
+	top := p.Y - height/2
+
+	return Rect{
+		Left:   left,
+		Top:    top,
+		Right:  left + width,
+		Bottom: top + height,
+	}
+}
+
+// NewClicker creates a new Clicker that sends input using the specified
+// Backend. The metrics are typically retrieved using GetClickMetrics.
+func NewClicker(backend Backend, metrics ClickMetrics) *Clicker {
+	return &Clicker{
+		backend: backend,
+		metrics: metrics,
+	}
+}
+
+// Clicker sends clicks, multi-clicks, holds and drags using timings
+// derived from the system's click metrics.
+//
+// If ctx is cancelled or an error occurs while a button is held down,
+// the button is released before the method returns.
+type Clicker struct {
+	backend Backend
+	metrics ClickMetrics
+}
+
+// Click presses and releases a mouse button.
+func (o *Clicker) Click(ctx context.Context, button MouseButton) error {
+	return o.MultiClick(ctx, button, 1)
+}
+
+// DoubleClick clicks a mouse button twice, well within the system's
+// double-click time.
+func (o *Clicker) DoubleClick(ctx context.Context, button MouseButton) error {
+	return o.MultiClick(ctx, button, 2)
+}
+
+// MultiClick clicks a mouse button n times. The clicks are spaced such
+// that each pair of consecutive clicks falls within the system's
+// double-click time, meaning n of 3 produces a triple-click. The cursor
+// is not moved between clicks, which keeps them within the double-click
+// rectangle.
+func (o *Clicker) MultiClick(ctx context.Context, button MouseButton, n int) error {
+	return o.multiClick(ctx, button, n, nil)
+}
+
+// MultiClickAt moves the cursor to each of the specified points and
+// clicks a mouse button there, producing a multi-click of len(points)
+// clicks. The timing is the same as MultiClick.
+//
+// Every point must be within the double-click rectangle of the first
+// point (refer to ClickMetrics.DoubleClickRect). Otherwise, an error
+// is returned before any input is sent.
+func (o *Clicker) MultiClickAt(ctx context.Context, button MouseButton, points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	rect := o.metrics.DoubleClickRect(points[0])
+	for _, p := range points[1:] {
+		if !rect.Contains(p) {
+			return fmt.Errorf("point %d,%d is outside of the double-click rectangle %+v",
+				p.X, p.Y, rect)
+		}
+	}
+
+	return o.multiClick(ctx, button, len(points), points)
+}
+
+// multiClick clicks a mouse button n times. If points is non-nil,
+// the cursor is moved to points[i] before the i-th click.
+func (o *Clicker) multiClick(ctx context.Context, button MouseButton, n int, points []Point) error {
+	click := []Input{NewMouseInput(button.DownInput()), NewMouseInput(button.UpInput())}
+	interval := o.metrics.DoubleClickTime / 5
+
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			err := sleepContext(ctx, interval)
+			if err != nil {
+				return err
+			}
+		}
+
+		if points != nil {
+			err := o.backend.SetCursorPos(points[i].X, points[i].Y)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := o.backend.SendInputs(click)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PressAndHold presses a mouse button, waits for the specified duration,
+// and then releases it.
+func (o *Clicker) PressAndHold(ctx context.Context, button MouseButton, d time.Duration) error {
+	return o.withButtonHeld(button, func() error {
+		return sleepContext(ctx, d)
+	})
+}
+
+// DragTo moves the cursor to from, presses a mouse button, moves the
+// cursor to the specified point, and then releases the button.
+//
+// If path is non-empty, the cursor follows its steps (e.g., those created
+// by MotionPlanner.Plan) before moving to the specified point. Otherwise,
+// the cursor moves directly to the point.
+//
+// Applications only begin a drag once the cursor leaves the drag
+// rectangle around the press (refer to ClickMetrics.DragRect). If the
+// first movement would stay within it, the cursor is first moved just
+// outside of the rectangle toward the movement's destination.
+func (o *Clicker) DragTo(ctx context.Context, button MouseButton, from Point, to Point, path []MotionStep) error {
+	err := o.backend.SetCursorPos(from.X, from.Y)
+	if err != nil {
+		return err
+	}
+
+	first := to
+	if len(path) > 0 {
+		first = path[0].Point
+	}
+
+	return o.withButtonHeld(button, func() error {
+		err := sleepContext(ctx, dragSettleDelay)
+		if err != nil {
+			return err
+		}
+
+		if o.metrics.DragRect(from).Contains(first) {
+			exit := o.metrics.dragExitPoint(from, first)
+			if first == from {
+				exit = o.metrics.dragExitPoint(from, to)
+			}
+
+			err = o.backend.SetCursorPos(exit.X, exit.Y)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, step := range path {
+			err := sleepContext(ctx, step.Delay)
+			if err != nil {
+				return err
+			}
+
+			err = o.backend.SetCursorPos(step.Point.X, step.Point.Y)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = o.backend.SetCursorPos(to.X, to.Y)
+		if err != nil {
+			return err
+		}
+
+		return sleepContext(ctx, dragSettleDelay)
+	})
+}
+
+// withButtonHeld presses a mouse button, calls fn, and then releases
+// the button regardless of fn's result.
+func (o *Clicker) withButtonHeld(button MouseButton, fn func() error) error {
+	err := o.backend.SendInputs([]Input{NewMouseInput(button.DownInput())})
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn()
+
+	err = o.backend.SendInputs([]Input{NewMouseInput(button.UpInput())})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
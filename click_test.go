@@ -0,0 +1,133 @@
+package user32util
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClickMetricsRects(t *testing.T) {
+	metrics := ClickMetrics{
+		DoubleClickWidth:  4,
+		DoubleClickHeight: 6,
+		DragWidth:         5,
+		DragHeight:        0,
+	}
+
+	exp := Rect{Left: -12, Top: 17, Right: -8, Bottom: 23}
+	if got := metrics.DoubleClickRect(Point{X: -10, Y: 20}); got != exp {
+		t.Fatalf("got double-click rect %+v, expected %+v", got, exp)
+	}
+
+	exp = Rect{Left: 98, Top: 100, Right: 103, Bottom: 101}
+	if got := metrics.DragRect(Point{X: 100, Y: 100}); got != exp {
+		t.Fatalf("got drag rect %+v, expected %+v", got, exp)
+	}
+}
+
+func TestClickMetricsDragExitPoint(t *testing.T) {
+	metrics := ClickMetrics{DragWidth: 4, DragHeight: 4}
+	from := Point{X: 100, Y: 100}
+
+	tests := []struct {
+		target Point
+		exp    Point
+	}{
+		{target: Point{X: 101, Y: 100}, exp: Point{X: 102, Y: 100}},
+		{target: Point{X: 99, Y: 100}, exp: Point{X: 97, Y: 100}},
+		{target: Point{X: 100, Y: 500}, exp: Point{X: 100, Y: 102}},
+		{target: Point{X: 50, Y: 75}, exp: Point{X: 97, Y: 98}},
+		{target: from, exp: Point{X: 102, Y: 100}},
+	}
+
+	for _, test := range tests {
+		got := metrics.dragExitPoint(from, test.target)
+		if got != test.exp {
+			t.Fatalf("exit point toward %+v is %+v, expected %+v", test.target, got, test.exp)
+		}
+
+		if metrics.DragRect(from).Contains(got) {
+			t.Fatalf("exit point %+v is inside of the drag rectangle", got)
+		}
+	}
+}
+
+func TestClickerDragToLeavesDragRect(t *testing.T) {
+	backend := &fakeBackend{}
+	clicker := NewClicker(backend, DefaultClickMetrics())
+	from := Point{X: 10, Y: 10}
+
+	path := []MotionStep{
+		{Point: Point{X: 11, Y: 10}},
+		{Point: Point{X: 30, Y: 10}},
+	}
+
+	err := clicker.DragTo(context.Background(), MouseButtonLeft, from, Point{X: 40, Y: 10}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Point{from, {X: 12, Y: 10}, {X: 11, Y: 10}, {X: 30, Y: 10}, {X: 40, Y: 10}}
+	if len(backend.moves) != len(exp) {
+		t.Fatalf("got moves %+v, expected %+v", backend.moves, exp)
+	}
+
+	for i := range exp {
+		if backend.moves[i] != exp[i] {
+			t.Fatalf("got moves %+v, expected %+v", backend.moves, exp)
+		}
+	}
+
+	if len(backend.batches) != 2 {
+		t.Fatalf("expected a press and a release, got %d batches", len(backend.batches))
+	}
+}
+
+func TestClickerDragToSkipsExitWhenFirstMoveLeaves(t *testing.T) {
+	backend := &fakeBackend{}
+	clicker := NewClicker(backend, DefaultClickMetrics())
+
+	err := clicker.DragTo(context.Background(), MouseButtonLeft, Point{}, Point{X: 0, Y: -50}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Point{{}, {X: 0, Y: -50}}
+	if len(backend.moves) != len(exp) || backend.moves[0] != exp[0] || backend.moves[1] != exp[1] {
+		t.Fatalf("got moves %+v, expected %+v", backend.moves, exp)
+	}
+}
+
+func TestClickerMultiClickAt(t *testing.T) {
+	metrics := ClickMetrics{
+		DoubleClickTime:   5 * time.Millisecond,
+		DoubleClickWidth:  4,
+		DoubleClickHeight: 4,
+	}
+
+	backend := &fakeBackend{}
+	clicker := NewClicker(backend, metrics)
+	points := []Point{{X: 50, Y: 50}, {X: 51, Y: 49}, {X: 49, Y: 51}}
+
+	err := clicker.MultiClickAt(context.Background(), MouseButtonRight, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backend.batches) != 3 || len(backend.moves) != 3 {
+		t.Fatalf("got %d clicks and %d moves, expected 3 of each",
+			len(backend.batches), len(backend.moves))
+	}
+
+	backend = &fakeBackend{}
+	clicker = NewClicker(backend, metrics)
+
+	err = clicker.MultiClickAt(context.Background(), MouseButtonRight, []Point{{X: 50, Y: 50}, {X: 52, Y: 50}})
+	if err == nil {
+		t.Fatal("expected an error for a point outside of the double-click rectangle")
+	}
+
+	if len(backend.batches) > 0 || len(backend.moves) > 0 {
+		t.Fatal("expected no input to be sent")
+	}
+}
@@ -0,0 +1,240 @@
+package user32util
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MouseMoveStats contains counters describing how a listener coalesced
+// or sampled WMMouseMove events.
+type MouseMoveStats struct {
+	// Received is the number of WMMouseMove events received by the hook.
+	Received uint64
+
+	// Delivered is the number of WMMouseMove events delivered to the
+	// listener's callback.
+	Delivered uint64
+
+	// Merged is the number of WMMouseMove events that were discarded,
+	// either because a newer move replaced them, because they were
+	// skipped by sampling, or because they were pending when the
+	// listener was released.
+	Merged uint64
+}
+
+// CoalesceMouseMoves configures a listener to deliver at most one
+// WMMouseMove event per interval (e.g., 16ms for one per frame at 60 Hz).
+// When moves arrive faster than that, only the most recent one is
+// delivered. All other mouse events, and keyboard events reported by the
+// same listener, are delivered unmodified and in order; a pending move is
+// always delivered before them.
+//
+// If no other event arrives, a pending move is delivered by a timer once
+// the interval elapses. The listener's callback may therefore be called
+// from a goroutine other than the hook's, but never concurrently.
+// A move that is pending when the listener is released is discarded.
+func CoalesceMouseMoves(interval time.Duration) ListenerOption {
+	return func(config *listenerConfig) {
+		config.moveInterval = interval
+	}
+}
+
+// SampleMouseMoves configures a listener to deliver only every nth
+// WMMouseMove event. All other events are delivered unmodified.
+func SampleMouseMoves(n int) ListenerOption {
+	return func(config *listenerConfig) {
+		config.moveSample = n
+	}
+}
+
+func newMoveCoalescer(interval time.Duration, sample int) *moveCoalescer {
+	return &moveCoalescer{
+		interval: interval,
+		sample:   sample,
+	}
+}
+
+// moveCoalescer implements the CoalesceMouseMoves and SampleMouseMoves
+// listener options. It serializes every event delivered by a listener,
+// including keyboard events, so that pending moves are delivered in order.
+//
+// Two locks are used: mu protects the coalescer's state and is never
+// held while a callback runs, and deliverMu serializes callbacks. When
+// both are needed, deliverMu is acquired first. This means the hook's
+// thread only waits for a callback running on the timer's goroutine if
+// it has an event to deliver.
+type moveCoalescer struct {
+	// Counters are accessed atomically. They are the first fields to
+	// guarantee 64-bit alignment on 32-bit platforms.
+	received  uint64
+	delivered uint64
+	merged    uint64
+
+	interval time.Duration
+	sample   int
+
+	deliverMu sync.Mutex
+
+	mu        sync.Mutex
+	count     int
+	lastSent  time.Time
+	pending   *LowLevelMouseEvent
+	pendingFn func(LowLevelMouseEvent)
+	timer     *time.Timer
+	closed    bool
+
+	// timerSeq identifies the current timer. A timer whose function
+	// runs after it was stopped or replaced does nothing.
+	timerSeq uint64
+}
+
+// mouse processes a mouse event, calling fn for events that are
+// delivered immediately.
+func (o *moveCoalescer) mouse(event LowLevelMouseEvent, fn func(LowLevelMouseEvent)) {
+	if event.MouseButtonAction() != WMMouseMove {
+		o.other(func() {
+			fn(event)
+		})
+		return
+	}
+
+	atomic.AddUint64(&o.received, 1)
+
+	o.mu.Lock()
+
+	if o.sample > 1 {
+		o.count++
+		if o.count%o.sample != 0 {
+			atomic.AddUint64(&o.merged, 1)
+			o.mu.Unlock()
+			return
+		}
+	}
+
+	now := time.Now()
+	if o.interval <= 0 || (o.pending == nil && now.Sub(o.lastSent) >= o.interval) {
+		o.lastSent = now
+		atomic.AddUint64(&o.delivered, 1)
+		o.mu.Unlock()
+
+		// A pending move that was taken by the timer is delivered
+		// first because the timer holds deliverMu while taking it.
+		o.deliverMu.Lock()
+		defer o.deliverMu.Unlock()
+
+		fn(event)
+		return
+	}
+
+	defer o.mu.Unlock()
+
+	if o.pending != nil {
+		atomic.AddUint64(&o.merged, 1)
+	}
+
+	// The event's Struct is only valid during the hook callback.
+	s := *event.Struct
+	event.Struct = &s
+	o.pending = &event
+	o.pendingFn = fn
+
+	if o.timer == nil && !o.closed {
+		o.timerSeq++
+		seq := o.timerSeq
+		o.timer = time.AfterFunc(o.interval-now.Sub(o.lastSent), func() {
+			o.flush(seq)
+		})
+	}
+}
+
+// other delivers a non-mouse event by calling fn after delivering
+// any pending move.
+func (o *moveCoalescer) other(fn func()) {
+	o.deliverMu.Lock()
+	defer o.deliverMu.Unlock()
+
+	o.mu.Lock()
+	event, pendingFn, ok := o.takePendingLocked()
+	o.mu.Unlock()
+
+	if ok {
+		pendingFn(event)
+	}
+
+	fn()
+}
+
+// flush delivers the pending move when the timer identified by seq fires.
+func (o *moveCoalescer) flush(seq uint64) {
+	o.deliverMu.Lock()
+	defer o.deliverMu.Unlock()
+
+	o.mu.Lock()
+	if o.closed || o.timer == nil || o.timerSeq != seq {
+		o.mu.Unlock()
+		return
+	}
+
+	event, pendingFn, ok := o.takePendingLocked()
+	o.mu.Unlock()
+
+	if ok {
+		pendingFn(event)
+	}
+}
+
+// takePendingLocked stops the timer and removes the pending move,
+// returning false if there is none. The caller must hold o.mu.
+func (o *moveCoalescer) takePendingLocked() (LowLevelMouseEvent, func(LowLevelMouseEvent), bool) {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+
+	if o.pending == nil {
+		return LowLevelMouseEvent{}, nil, false
+	}
+
+	event := *o.pending
+	o.pending = nil
+	o.lastSent = time.Now()
+	atomic.AddUint64(&o.delivered, 1)
+
+	return event, o.pendingFn, true
+}
+
+// close stops the timer and discards the pending move. The coalescer
+// does not start new timers once it is closed.
+func (o *moveCoalescer) close() {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+
+	if o.pending != nil {
+		o.pending = nil
+		atomic.AddUint64(&o.merged, 1)
+	}
+}
+
+func (o *moveCoalescer) stats() MouseMoveStats {
+	if o == nil {
+		return MouseMoveStats{}
+	}
+
+	return MouseMoveStats{
+		Received:  atomic.LoadUint64(&o.received),
+		Delivered: atomic.LoadUint64(&o.delivered),
+		Merged:    atomic.LoadUint64(&o.merged),
+	}
+}
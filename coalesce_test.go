@@ -0,0 +1,205 @@
+package user32util
+
+import (
+	"testing"
+	"time"
+)
+
+// coalesceRecorder records the events delivered by a moveCoalescer.
+type coalesceRecorder struct {
+	events []string
+}
+
+func (o *coalesceRecorder) mouse(event LowLevelMouseEvent) {
+	if event.MouseButtonAction() == WMMouseMove {
+		o.events = append(o.events, "move "+string(rune('0'+event.Struct.Point.X)))
+		return
+	}
+
+	o.events = append(o.events, MessageName(event.WParam))
+}
+
+func (o *coalesceRecorder) check(t *testing.T, exp ...string) {
+	t.Helper()
+
+	if len(o.events) != len(exp) {
+		t.Fatalf("got events %q, expected %q", o.events, exp)
+	}
+
+	for i := range exp {
+		if o.events[i] != exp[i] {
+			t.Fatalf("got events %q, expected %q", o.events, exp)
+		}
+	}
+}
+
+func coalesceMove(x int32) LowLevelMouseEvent {
+	return LowLevelMouseEvent{
+		WParam: uintptr(WMMouseMove),
+		Struct: &MsllHookStruct{Point: Point{X: x}},
+	}
+}
+
+func checkMoveStats(t *testing.T, coalescer *moveCoalescer, exp MouseMoveStats) {
+	t.Helper()
+
+	if got := coalescer.stats(); got != exp {
+		t.Fatalf("got stats %+v, expected %+v", got, exp)
+	}
+}
+
+func TestMoveCoalescerMergesMoves(t *testing.T) {
+	// The interval is long enough that the timer never fires.
+	coalescer := newMoveCoalescer(time.Hour, 0)
+	defer coalescer.close()
+
+	var recorder coalesceRecorder
+
+	coalescer.mouse(coalesceMove(1), recorder.mouse)
+	coalescer.mouse(coalesceMove(2), recorder.mouse)
+	coalescer.mouse(coalesceMove(3), recorder.mouse)
+	coalescer.mouse(coalesceMove(4), recorder.mouse)
+
+	recorder.check(t, "move 1")
+	checkMoveStats(t, coalescer, MouseMoveStats{Received: 4, Delivered: 1, Merged: 2})
+
+	if coalescer.pending == nil || coalescer.pending.Struct.Point.X != 4 {
+		t.Fatalf("expected the latest move to be pending, got %+v", coalescer.pending)
+	}
+}
+
+func TestMoveCoalescerFlushesBeforeOtherEvents(t *testing.T) {
+	coalescer := newMoveCoalescer(time.Hour, 0)
+	defer coalescer.close()
+
+	var recorder coalesceRecorder
+
+	coalescer.mouse(coalesceMove(1), recorder.mouse)
+	coalescer.mouse(coalesceMove(2), recorder.mouse)
+	coalescer.mouse(coalesceMove(3), recorder.mouse)
+	coalescer.mouse(LowLevelMouseEvent{
+		WParam: uintptr(WMLButtonDown),
+		Struct: &MsllHookStruct{},
+	}, recorder.mouse)
+
+	recorder.check(t, "move 1", "move 3", MessageName(uintptr(WMLButtonDown)))
+
+	coalescer.mouse(coalesceMove(4), recorder.mouse)
+	coalescer.other(func() {
+		recorder.events = append(recorder.events, "key")
+	})
+
+	recorder.check(t, "move 1", "move 3", MessageName(uintptr(WMLButtonDown)), "move 4", "key")
+	checkMoveStats(t, coalescer, MouseMoveStats{Received: 4, Delivered: 3, Merged: 1})
+
+	if coalescer.timer != nil {
+		t.Fatal("expected the timer to be stopped once nothing is pending")
+	}
+}
+
+func TestMoveCoalescerSamplesMoves(t *testing.T) {
+	coalescer := newMoveCoalescer(0, 3)
+	defer coalescer.close()
+
+	var recorder coalesceRecorder
+
+	for x := int32(1); x <= 7; x++ {
+		coalescer.mouse(coalesceMove(x), recorder.mouse)
+	}
+
+	recorder.check(t, "move 3", "move 6")
+	checkMoveStats(t, coalescer, MouseMoveStats{Received: 7, Delivered: 2, Merged: 5})
+}
+
+func TestMoveCoalescerTimerFlush(t *testing.T) {
+	coalescer := newMoveCoalescer(time.Hour, 0)
+	defer coalescer.close()
+
+	var recorder coalesceRecorder
+
+	coalescer.mouse(coalesceMove(1), recorder.mouse)
+	coalescer.mouse(coalesceMove(2), recorder.mouse)
+
+	stale := coalescer.timerSeq
+
+	// Delivering another event stops the timer. A new timer is started
+	// by the next pending move.
+	coalescer.other(func() {})
+	coalescer.mouse(coalesceMove(3), recorder.mouse)
+
+	if coalescer.timerSeq == stale {
+		t.Fatal("expected a new timer to be started")
+	}
+
+	coalescer.flush(stale)
+
+	recorder.check(t, "move 1", "move 2")
+
+	if coalescer.timer == nil || coalescer.pending == nil {
+		t.Fatal("expected a stale timer not to affect the new timer")
+	}
+
+	coalescer.flush(coalescer.timerSeq)
+
+	recorder.check(t, "move 1", "move 2", "move 3")
+	checkMoveStats(t, coalescer, MouseMoveStats{Received: 3, Delivered: 3})
+
+	if coalescer.timer != nil {
+		t.Fatal("expected the timer to be cleared after it fired")
+	}
+}
+
+func TestMoveCoalescerTimerDelivers(t *testing.T) {
+	coalescer := newMoveCoalescer(time.Millisecond, 0)
+	defer coalescer.close()
+
+	delivered := make(chan int32, 2)
+	fn := func(event LowLevelMouseEvent) {
+		delivered <- event.Struct.Point.X
+	}
+
+	coalescer.mouse(coalesceMove(1), fn)
+	coalescer.mouse(coalesceMove(2), fn)
+
+	for _, exp := range []int32{1, 2} {
+		select {
+		case x := <-delivered:
+			if x != exp {
+				t.Fatalf("got move %d, expected %d", x, exp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for move %d", exp)
+		}
+	}
+}
+
+func TestMoveCoalescerClose(t *testing.T) {
+	coalescer := newMoveCoalescer(time.Hour, 0)
+
+	var recorder coalesceRecorder
+
+	coalescer.mouse(coalesceMove(1), recorder.mouse)
+	coalescer.mouse(coalesceMove(2), recorder.mouse)
+
+	seq := coalescer.timerSeq
+
+	coalescer.close()
+
+	if coalescer.timer != nil || coalescer.pending != nil {
+		t.Fatal("expected close to stop the timer and discard the pending move")
+	}
+
+	coalescer.flush(seq)
+	coalescer.mouse(coalesceMove(3), recorder.mouse)
+
+	if coalescer.timer != nil {
+		t.Fatal("expected no timer to be started once closed")
+	}
+
+	recorder.check(t, "move 1")
+	checkMoveStats(t, coalescer, MouseMoveStats{Received: 3, Delivered: 1, Merged: 1})
+
+	// A nil coalescer is used by listeners without the options.
+	var none *moveCoalescer
+	none.close()
+}
@@ -2,13 +2,23 @@ package user32util
 
 import (
 	"golang.org/x/sys/windows"
+	"log/slog"
 	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // Various WM codes.
 const (
 	wmQuit = 0x0012
+	wmApp  = 0x8000
+
+	// wmReinstallHook is posted to a hook's thread to reinstall one of
+	// its hooks. Its wParam is the index of the hook.
+	wmReinstallHook = wmApp + 1
 )
 
 const (
@@ -22,10 +32,34 @@ const (
 	sendInputName           = "SendInput"
 	postThreadMessageWName  = "PostThreadMessageW"
 	setCursorPosName        = "SetCursorPos"
+	enumDisplayMonitorsName = "EnumDisplayMonitors"
+	getMonitorInfoWName     = "GetMonitorInfoW"
+	monitorFromPointName    = "MonitorFromPoint"
+	getDpiForWindowName     = "GetDpiForWindow"
+	getDoubleClickTimeName  = "GetDoubleClickTime"
+	getSystemMetricsName    = "GetSystemMetrics"
+	getCursorPosName        = "GetCursorPos"
+	getPhysCursorPosName    = "GetPhysicalCursorPos"
+	clipCursorName          = "ClipCursor"
+	getClipCursorName       = "GetClipCursor"
+	showCursorName          = "ShowCursor"
+	getCursorInfoName       = "GetCursorInfo"
+	getAsyncKeyStateName    = "GetAsyncKeyState"
+	getKeyStateName         = "GetKeyState"
+	getKeyboardStateName    = "GetKeyboardState"
+	getForegroundWindowName = "GetForegroundWindow"
 )
 
 // LoadUser32DLL loads the user32 DLL into memory.
 func LoadUser32DLL() (*User32DLL, error) {
+	return LoadUser32DLLWithConfig(Config{})
+}
+
+// LoadUser32DLLWithConfig loads the user32 DLL into memory using the
+// specified Config.
+func LoadUser32DLLWithConfig(config Config) (*User32DLL, error) {
+	logger := config.logger()
+
 	// TODO: Hack to avoid using unsafe 'windows.LoadDLL()' while
 	//  retaining full control over when a DLL is loaded.
 	temp := windows.LazyDLL{
@@ -34,6 +68,7 @@ func LoadUser32DLL() (*User32DLL, error) {
 	}
 	err := temp.Load()
 	if err != nil {
+		logger.Error("failed to load dll", "dll", user32DllName, "error", err)
 		return nil, err
 	}
 
@@ -42,79 +77,165 @@ func LoadUser32DLL() (*User32DLL, error) {
 		Handle: windows.Handle(temp.Handle()),
 	}
 
-	setWindowsHookExW, err := user32.FindProc(setWindowsHookExWName)
-	if err != nil {
-		return nil, err
+	dll := &User32DLL{
+		user32:        user32,
+		logger:        logger,
+		logKeystrokes: config.LogKeystrokes,
 	}
 
-	call, err := user32.FindProc(callNextHookExName)
-	if err != nil {
-		return nil, err
+	for i, spec := range user32Procs {
+		dll.procs[i] = &lazyProc{
+			dll:    user32,
+			spec:   spec,
+			logger: logger,
+		}
+
+		if spec.required {
+			err := dll.procs[i].find()
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	unhook, err := user32.FindProc(unhookWindowsHookExName)
-	if err != nil {
-		return nil, err
+	logger.Debug("loaded dll", "dll", user32DllName)
+
+	return dll, nil
+}
+
+// User32DLL represents the user32 DLL and the procedures declared
+// in user32Procs.
+type User32DLL struct {
+	user32        *windows.DLL
+	logger        *slog.Logger
+	logKeystrokes bool
+	procs         [numProcs]*lazyProc
+	shared        bool
+
+	// hookThreads is the number of hook threads that have not been
+	// released. It is accessed atomically.
+	hookThreads int32
+}
+
+// proc returns the specified procedure.
+func (o *User32DLL) proc(id procID) *lazyProc {
+	return o.procs[id]
+}
+
+// Capabilities reports the version of Windows and which of the
+// procedures used by this library are available, including those of
+// system DLLs other than user32 (e.g., shcore's GetDpiForMonitor).
+// Optional procedures that have not been called yet are resolved.
+func (o *User32DLL) Capabilities() Capabilities {
+	capabilities := Capabilities{
+		Procs: make([]ProcCapability, 0, len(o.procs)+len(systemProcs)),
 	}
 
-	getMessageW, err := user32.FindProc(getMessageWName)
-	if err != nil {
-		return nil, err
+	capabilities.Version, _ = GetWindowsVersion()
+
+	for _, proc := range o.procs {
+		capabilities.Procs = append(capabilities.Procs, ProcCapability{
+			DLL:       user32DllName,
+			Name:      proc.spec.name,
+			Required:  proc.spec.required,
+			Available: proc.find() == nil,
+		})
 	}
 
-	sendInput, err := user32.FindProc(sendInputName)
-	if err != nil {
-		return nil, err
+	for _, proc := range systemProcs {
+		capabilities.Procs = append(capabilities.Procs, ProcCapability{
+			DLL:       proc.dll.Name,
+			Name:      proc.spec.name,
+			Required:  proc.spec.required,
+			Available: proc.dll.NewProc(proc.spec.name).Find() == nil,
+		})
 	}
 
-	postThreadMessageW, err := user32.FindProc(postThreadMessageWName)
-	if err != nil {
-		return nil, err
+	return capabilities
+}
+
+// Release releases the underlying DLL. If the User32DLL was obtained from
+// Acquire, Release releases one reference instead, and the DLL is only
+// released when the last reference is released.
+//
+// ErrHooksInstalled is returned if the DLL would be released while hooks
+// created using it (e.g., by a listener or Hub) are still installed.
+func (o *User32DLL) Release() error {
+	if o.shared {
+		return releaseShared(o)
+	}
+
+	return o.release()
+}
+
+func (o *User32DLL) release() error {
+	if atomic.LoadInt32(&o.hookThreads) > 0 {
+		return ErrHooksInstalled
 	}
 
-	setCursorPos, err := user32.FindProc(setCursorPosName)
+	err := o.user32.Release()
 	if err != nil {
-		return nil, err
+		o.logger.Error("failed to release dll", "dll", user32DllName, "error", err)
+		return err
 	}
 
-	return &User32DLL{
-		user32:              user32,
-		setWindowsHookExW:   setWindowsHookExW,
-		callNextHookEx:      call,
-		unhookWindowsHookEx: unhook,
-		getMessageW:         getMessageW,
-		sendInput:           sendInput,
-		postThreadMessageW:  postThreadMessageW,
-		setCursorPos:        setCursorPos,
-	}, nil
+	o.logger.Debug("released dll", "dll", user32DllName)
+
+	return nil
 }
 
-// User32DLL represents the user32 DLL, mapping several of its procedures to
-// this struct's fields.
-type User32DLL struct {
-	user32              *windows.DLL
-	setWindowsHookExW   *windows.Proc
-	callNextHookEx      *windows.Proc
-	unhookWindowsHookEx *windows.Proc
-	getMessageW         *windows.Proc
-	sendInput           *windows.Proc
-	postThreadMessageW  *windows.Proc
-	setCursorPos        *windows.Proc
+// onHookCalledFunc defines what happens when a Windows hook created using
+// "SetWindowsHookEx*()" is called. Returning true blocks the event from
+// being passed to the rest of the hook chain and the target window.
+type onHookCalledFunc func(nCode int, wParam uintptr, lParam uintptr) bool
+
+// hookSpec describes a single Windows hook to install.
+type hookSpec struct {
+	hookID   int
+	callBack onHookCalledFunc
+
+	// slot, if non-nil, provides the hook's callback instead of
+	// a new one being created.
+	slot *hookProcSlot
 }
 
-// Release releases the underlying DLL.
-func (o *User32DLL) Release() error {
-	return o.user32.Release()
+// hookProcSlot is a callback that is created once and reused by
+// successive hook threads. Callbacks created by windows.NewCallback are
+// never freed, meaning code that installs hooks repeatedly (e.g., Hub)
+// must reuse them rather than create new ones.
+//
+// A slot holds a single hook procedure at a time, so hooks that may
+// be installed at the same time must use different slots.
+type hookProcSlot struct {
+	once     sync.Once
+	callback uintptr
+
+	// proc holds a func(int, uintptr, uintptr) uintptr.
+	proc atomic.Value
 }
 
-// onHookCalledFunc defines what happens when a Windows hook created using
-// "SetWindowsHookEx*()" is called.
-type onHookCalledFunc func(nCode int, wParam uintptr, lParam uintptr)
+// use sets the slot's hook procedure, returning the slot's callback.
+func (o *hookProcSlot) use(proc func(nCode int, wParam uintptr, lParam uintptr) uintptr) uintptr {
+	o.proc.Store(proc)
+
+	o.once.Do(func() {
+		o.callback = windows.NewCallback(func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+			return o.proc.Load().(func(int, uintptr, uintptr) uintptr)(nCode, wParam, lParam)
+		})
+	})
+
+	return o.callback
+}
 
 // setWindowsHookExW wraps the 'SetWindowsHookExW()' system call, creating
-// a new Windows hook for the given hook ID and callback. On success,
-// it returns a handle to the hook, the ID of thread associated with the hook,
-// and a channel that is written to when the hook exits.
+// new Windows hooks for the given hook IDs and callbacks. All of the hooks
+// are installed on a single, dedicated OS thread, meaning their callbacks
+// are called serially in the order that Windows reports the events.
+//
+// On success, it returns a hookThread that holds the hooks' handles,
+// the ID of the thread associated with the hooks, and a channel that is
+// written to when the thread's message loop exits. If any hook fails to
+// install, the hooks that were installed are removed.
 //
 // From the Windows API documentation:
 //	Installs an application-defined hook procedure into a hook chain.
@@ -124,57 +245,254 @@ type onHookCalledFunc func(nCode int, wParam uintptr, lParam uintptr)
 //
 // Refer to the following Windows API document for more information:
 // https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-setwindowshookexw
-func setWindowsHookExW(hookID int, callBack onHookCalledFunc, user32 *User32DLL) (uintptr, uint32, <-chan error, error) {
-	ready := make(chan hookSetupResult)
+func setWindowsHookExW(specs []hookSpec, config listenerConfig, user32 *User32DLL) (*hookThread, error) {
+	ready := make(chan error)
 	done := make(chan error, 1)
 
+	thread := &hookThread{
+		user32:    user32,
+		specs:     specs,
+		callbacks: make([]uintptr, len(specs)),
+		handles:   make([]uintptr, len(specs)),
+		done:      done,
+		stats:     config.stats,
+	}
+
+	if thread.stats == nil {
+		thread.stats = newHookStats()
+	}
+
+	if config.watchdog != nil {
+		thread.watchdog = newWatchdog(thread, *config.watchdog)
+	}
+
+	for i, spec := range specs {
+		if spec.slot != nil {
+			thread.callbacks[i] = spec.slot.use(thread.newHookProc(i))
+		} else {
+			thread.callbacks[i] = windows.NewCallback(thread.newHookProc(i))
+		}
+	}
+
+	// Count the thread before installing its hooks, so that the DLL
+	// cannot be released while they are being installed.
+	atomic.AddInt32(&user32.hookThreads, 1)
+
 	go func() {
 		runtime.LockOSThread()
 
-		var hookHandle uintptr
-		var err error
-		hookHandle, _, err = user32.setWindowsHookExW.Call(
-			uintptr(hookID),
-			windows.NewCallback(func(nCode int, wParam uintptr, lParam uintptr) uintptr {
-				callBack(nCode, wParam, lParam)
-
-				nextHookCallResult, _, _ := user32.callNextHookEx.Call(hookHandle, uintptr(nCode), wParam, lParam)
-
-				return nextHookCallResult
-			}),
-			0,
-			0,
-		)
-		if hookHandle == 0 {
-			ready <- hookSetupResult{err:err}
-			return
-		}
+		thread.tid = windows.GetCurrentThreadId()
 
-		ready <- hookSetupResult{
-			handle: hookHandle,
-			tid:    windows.GetCurrentThreadId(),
+		for i := range specs {
+			err := thread.install(i)
+			if err != nil {
+				user32.logger.Error("failed to install hook",
+					"hook", hookName(specs[i].hookID), "thread", thread.tid, "error", err)
+				thread.unhookAll()
+				ready <- err
+				return
+			}
+
+			user32.logger.Info("installed hook",
+				"hook", hookName(specs[i].hookID), "thread", thread.tid)
 		}
 
+		ready <- nil
+
 		// Needed to actually get events. Must be on same thread as hook.
+		// GetMessageW only returns when a message is posted to the
+		// thread; hook callbacks are called while it waits.
 		var msg Msg
-		for r, _, _ := user32.getMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0); r != 0; {
+		for {
+			r, _, err := user32.proc(procGetMessageW).Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r) == -1 {
+				err = newCallError("get message", getMessageWName, err)
+				user32.logger.Error("hook message loop failed", "thread", thread.tid, "error", err)
+				done <- err
+				return
+			}
+
+			if r == 0 {
+				break
+			}
+
+			if uint32(msg.Message) == wmReinstallHook {
+				thread.reinstall(int(msg.WParam))
+			}
 		}
 
+		user32.logger.Info("hook message loop exited", "thread", thread.tid)
+
 		done <- nil
 	}()
 
-	result := <-ready
-	if result.err != nil {
-		return 0, 0, nil, result.err
+	err := <-ready
+	if err != nil {
+		atomic.AddInt32(&user32.hookThreads, -1)
+		return nil, err
+	}
+
+	if thread.watchdog != nil {
+		thread.watchdog.start()
+	}
+
+	return thread, nil
+}
+
+// hookThread represents one or more hooks installed by setWindowsHookExW.
+type hookThread struct {
+	user32    *User32DLL
+	specs     []hookSpec
+	callbacks []uintptr
+	tid       uint32
+	done      <-chan error
+	watchdog  *watchdog
+	stats     *hookStats
+	released  uint32
+
+	// handles are accessed atomically because the hook's thread
+	// replaces them when a hook is reinstalled.
+	handles []uintptr
+}
+
+// newHookProc returns the hook procedure for the ith hook spec.
+// The procedure is created once and reused if the hook is reinstalled.
+func (o *hookThread) newHookProc(i int) func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+	spec := o.specs[i]
+
+	return func(nCode int, wParam uintptr, lParam uintptr) uintptr {
+		if nCode == 0 && o.watchdog != nil && o.watchdog.isProbe(i, lParam) {
+			return 1
+		}
+
+		start := time.Now()
+		blocked := spec.callBack(nCode, wParam, lParam)
+		elapsed := time.Since(start)
+
+		if nCode == 0 {
+			o.stats.observe(wParam, blocked, elapsed)
+		}
+
+		if o.watchdog != nil {
+			o.watchdog.observeCallback(i, elapsed)
+		}
+
+		if blocked {
+			return 1
+		}
+
+		nextHookCallResult, _, _ := o.user32.proc(procCallNextHookEx).Call(atomic.LoadUintptr(&o.handles[i]), uintptr(nCode), wParam, lParam)
+
+		return nextHookCallResult
+	}
+}
+
+// install installs the ith hook. It must be called on the hook's thread.
+func (o *hookThread) install(i int) error {
+	handle, _, err := o.user32.proc(procSetWindowsHookExW).Call(uintptr(o.specs[i].hookID), o.callbacks[i], 0, 0)
+	if handle == 0 {
+		return newCallError("install hook "+hookName(o.specs[i].hookID), setWindowsHookExWName, err)
 	}
 
-	return result.handle, result.tid, done, nil
+	atomic.StoreUintptr(&o.handles[i], handle)
+
+	return nil
 }
 
-type hookSetupResult struct {
-	handle uintptr
-	tid    uint32
-	err    error
+// reinstall removes and then reinstalls the ith hook. It must be called
+// on the hook's thread.
+func (o *hookThread) reinstall(i int) {
+	if i < 0 || i >= len(o.specs) {
+		return
+	}
+
+	handle := atomic.SwapUintptr(&o.handles[i], 0)
+	if handle != 0 {
+		o.user32.proc(procUnhookWindowsHookEx).Call(handle)
+	}
+
+	err := o.install(i)
+	if err != nil {
+		o.user32.logger.Error("failed to reinstall hook",
+			"hook", hookName(o.specs[i].hookID), "thread", o.tid, "error", err)
+	} else {
+		o.user32.logger.Warn("reinstalled hook",
+			"hook", hookName(o.specs[i].hookID), "thread", o.tid)
+	}
+
+	if o.watchdog != nil {
+		o.watchdog.reinstalled(i, err)
+	}
+}
+
+// requestReinstall asks the hook's thread to reinstall the ith hook.
+func (o *hookThread) requestReinstall(i int) error {
+	ret, _, err := o.user32.proc(procPostThreadMessageW).Call(uintptr(o.tid), wmReinstallHook, uintptr(i), 0)
+	if ret == 0 {
+		return newCallError("request hook reinstall", postThreadMessageWName, err)
+	}
+
+	return nil
+}
+
+func (o *hookThread) unhookAll() {
+	for i := range o.handles {
+		handle := atomic.SwapUintptr(&o.handles[i], 0)
+		if handle == 0 {
+			continue
+		}
+
+		ret, _, err := o.user32.proc(procUnhookWindowsHookEx).Call(handle)
+		if ret == 0 {
+			o.user32.logger.Warn("failed to remove hook",
+				"hook", hookName(o.specs[i].hookID), "thread", o.tid, "error", err)
+			continue
+		}
+
+		o.user32.logger.Info("removed hook",
+			"hook", hookName(o.specs[i].hookID), "thread", o.tid)
+	}
+}
+
+// hookName returns the name of a hook ID as used in the Windows
+// API documentation.
+func hookName(hookID int) string {
+	switch hookID {
+	case whKeyboardLl:
+		return "WH_KEYBOARD_LL"
+	case whMouseLl:
+		return "WH_MOUSE_LL"
+	default:
+		return strconv.Itoa(hookID)
+	}
+}
+
+// watchdogEvents returns the watchdog's events channel, or nil if the
+// thread has no watchdog.
+func (o *hookThread) watchdogEvents() <-chan WatchdogEvent {
+	if o.watchdog == nil {
+		return nil
+	}
+
+	return o.watchdog.events
+}
+
+// release stops the thread's message loop and removes its hooks.
+// Subsequent calls do nothing.
+func (o *hookThread) release() {
+	if !atomic.CompareAndSwapUint32(&o.released, 0, 1) {
+		return
+	}
+
+	defer atomic.AddInt32(&o.user32.hookThreads, -1)
+
+	if o.watchdog != nil {
+		o.watchdog.stop()
+	}
+
+	o.user32.proc(procPostThreadMessageW).Call(uintptr(o.tid), wmQuit, 0, 0)
+
+	o.unhookAll()
 }
 
 // From the Windows API documentation:
@@ -0,0 +1,77 @@
+package user32util
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Config configures a User32DLL.
+//
+// Refer to LoadUser32DLLWithConfig for more information.
+type Config struct {
+	// Logger receives log messages about loading the DLL, installing
+	// and removing hooks, hook message loops and failed input sends.
+	// Nothing is logged if it is nil.
+	Logger *slog.Logger
+
+	// LogKeystrokes includes the contents of keyboard inputs (e.g., the
+	// virtual-key code or character) in log messages. Keystrokes may
+	// contain passwords, so they are redacted by default.
+	LogKeystrokes bool
+}
+
+func (o Config) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(discardHandler{})
+	}
+
+	return o.Logger
+}
+
+// discardHandler is a slog.Handler that discards all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (o discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return o }
+func (o discardHandler) WithGroup(string) slog.Handler           { return o }
+
+// inputsLogValue is a slog.LogValuer that describes a slice of Input.
+// Keyboard inputs are redacted unless keystrokes is true.
+type inputsLogValue struct {
+	inputs     []Input
+	keystrokes bool
+}
+
+func (o inputsLogValue) LogValue() slog.Value {
+	attrs := make([]slog.Attr, len(o.inputs))
+
+	for i, input := range o.inputs {
+		var desc string
+
+		switch input.Type {
+		case InputMouse:
+			mouse, _ := input.MouseInput()
+			desc = fmt.Sprintf("mouse dx=%d dy=%d data=%d flags=0x%X",
+				mouse.Dx, mouse.Dy, int32(mouse.MouseData), mouse.DwFlags)
+		case InputKeyboard:
+			if !o.keystrokes {
+				desc = "keyboard [redacted]"
+				break
+			}
+
+			kb, _ := input.KeybdInput()
+			desc = fmt.Sprintf("keyboard vk=0x%X scan=0x%X flags=0x%X",
+				kb.WVK, kb.WScan, kb.DwFlags)
+		case InputHardware:
+			desc = "hardware"
+		default:
+			desc = fmt.Sprintf("unknown type %d", input.Type)
+		}
+
+		attrs[i] = slog.String(fmt.Sprint(i), desc)
+	}
+
+	return slog.GroupValue(attrs...)
+}
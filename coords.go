@@ -0,0 +1,149 @@
+package user32util
+
+import (
+	"fmt"
+	"math"
+)
+
+// absoluteRange is the number of distinct normalized absolute
+// coordinates along each axis (0 through 65535).
+const absoluteRange = 65536
+
+// From the Windows API documentation:
+//	The RECT structure defines a rectangle by the coordinates of
+//	its upper-left and lower-right corners.
+//
+// Like the Windows structure, the Right and Bottom edges are exclusive.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/windef/ns-windef-rect
+type Rect struct {
+	Left   int32
+	Top    int32
+	Right  int32
+	Bottom int32
+}
+
+// Width returns the width of the rectangle.
+func (o Rect) Width() int32 {
+	return o.Right - o.Left
+}
+
+// Height returns the height of the rectangle.
+func (o Rect) Height() int32 {
+	return o.Bottom - o.Top
+}
+
+// Contains returns true if p is within the rectangle.
+func (o Rect) Contains(p Point) bool {
+	return p.X >= o.Left && p.X < o.Right && p.Y >= o.Top && p.Y < o.Bottom
+}
+
+// Center returns the point at the center of the rectangle.
+func (o Rect) Center() Point {
+	return Point{
+		X: o.Left + o.Width()/2,
+		Y: o.Top + o.Height()/2,
+	}
+}
+
+// ScreenLayout describes the coordinate spaces that absolute mouse input
+// is mapped onto.
+//
+// Without MouseEventFVirtualDesk, normalized coordinates are mapped onto
+// the primary monitor. With MouseEventFVirtualDesk, they are mapped onto
+// the virtual screen (the bounding rectangle of all monitors), whose origin
+// is negative when a secondary monitor is positioned to the left of or
+// above the primary monitor.
+type ScreenLayout struct {
+	// Primary is the primary monitor's bounds in physical pixels.
+	// Its upper-left corner is always 0,0.
+	Primary Rect
+
+	// VirtualScreen is the virtual screen's bounds in physical pixels.
+	VirtualScreen Rect
+
+	// Scale is the DPI scale factor of the points passed to the
+	// layout's methods (e.g., 1.5 for 144 DPI). Points are multiplied
+	// by Scale to convert them into physical pixels. A value of zero
+	// is treated as 1.
+	Scale float64
+}
+
+// ToPhysical converts p into physical pixels using the layout's Scale.
+func (o ScreenLayout) ToPhysical(p Point) Point {
+	if o.Scale == 0 || o.Scale == 1 {
+		return p
+	}
+
+	return Point{
+		X: int32(math.Round(float64(p.X) * o.Scale)),
+		Y: int32(math.Round(float64(p.Y) * o.Scale)),
+	}
+}
+
+// AbsoluteMouseInput returns a MouseInput that moves the cursor to p using
+// coordinates normalized to the primary monitor.
+func (o ScreenLayout) AbsoluteMouseInput(p Point) (MouseInput, error) {
+	dx, dy, err := NormalizeAbsolute(o.ToPhysical(p), o.Primary)
+	if err != nil {
+		return MouseInput{}, err
+	}
+
+	return MouseInput{
+		Dx:      dx,
+		Dy:      dy,
+		DwFlags: MouseEventFMove | MouseEventFAbsolute,
+	}, nil
+}
+
+// VirtualDeskMouseInput returns a MouseInput that moves the cursor to p
+// using coordinates normalized to the virtual screen.
+func (o ScreenLayout) VirtualDeskMouseInput(p Point) (MouseInput, error) {
+	dx, dy, err := NormalizeAbsolute(o.ToPhysical(p), o.VirtualScreen)
+	if err != nil {
+		return MouseInput{}, err
+	}
+
+	return MouseInput{
+		Dx:      dx,
+		Dy:      dy,
+		DwFlags: MouseEventFMove | MouseEventFAbsolute | MouseEventFVirtualDesk,
+	}, nil
+}
+
+// NormalizeAbsolute converts a pixel coordinate into the normalized
+// 0 through 65535 range used by MouseEventFAbsolute, relative to bounds.
+//
+// Windows maps a normalized coordinate back to a pixel by computing
+// floor(normalized * size / 65536). Rounding the normalized value up
+// guarantees that this mapping lands on the intended pixel, which is not
+// the case when the value is truncated or scaled by 65535.
+//
+// Refer to DenormalizeAbsolute for the inverse operation.
+func NormalizeAbsolute(p Point, bounds Rect) (int32, int32, error) {
+	if bounds.Width() <= 0 || bounds.Height() <= 0 || bounds.Width() > absoluteRange || bounds.Height() > absoluteRange {
+		return 0, 0, fmt.Errorf("invalid bounds for absolute coordinates: %+v", bounds)
+	}
+
+	if !bounds.Contains(p) {
+		return 0, 0, fmt.Errorf("point %+v is outside of bounds %+v", p, bounds)
+	}
+
+	return normalizeAxis(p.X-bounds.Left, bounds.Width()),
+		normalizeAxis(p.Y-bounds.Top, bounds.Height()),
+		nil
+}
+
+func normalizeAxis(offset int32, size int32) int32 {
+	return int32((int64(offset)*absoluteRange + int64(size) - 1) / int64(size))
+}
+
+// DenormalizeAbsolute converts normalized absolute coordinates into the
+// pixel that Windows moves the cursor to, relative to bounds.
+func DenormalizeAbsolute(dx int32, dy int32, bounds Rect) Point {
+	return Point{
+		X: bounds.Left + int32(int64(dx)*int64(bounds.Width())/absoluteRange),
+		Y: bounds.Top + int32(int64(dy)*int64(bounds.Height())/absoluteRange),
+	}
+}
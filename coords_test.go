@@ -0,0 +1,117 @@
+package user32util
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNormalizeAbsoluteRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	bounds := []Rect{
+		{Left: 0, Top: 0, Right: 1920, Bottom: 1080},
+		{Left: -1280, Top: -1024, Right: 1920, Bottom: 1080},
+		{Left: -3840, Top: 0, Right: 0, Bottom: 2160},
+		{Left: 0, Top: -1, Right: 1, Bottom: 0},
+		{Left: -7, Top: -13, Right: 1359, Bottom: 755},
+		{Left: 0, Top: 0, Right: absoluteRange, Bottom: absoluteRange},
+	}
+
+	for i := 0; i < 500; i++ {
+		width := 1 + random.Int31n(absoluteRange)
+		height := 1 + random.Int31n(absoluteRange)
+		left := random.Int31n(40000) - 20000
+		top := random.Int31n(40000) - 20000
+
+		bounds = append(bounds, Rect{
+			Left:   left,
+			Top:    top,
+			Right:  left + width,
+			Bottom: top + height,
+		})
+	}
+
+	for _, b := range bounds {
+		points := []Point{
+			{X: b.Left, Y: b.Top},
+			{X: b.Right - 1, Y: b.Bottom - 1},
+			{X: b.Left, Y: b.Bottom - 1},
+			{X: b.Right - 1, Y: b.Top},
+			b.Center(),
+		}
+
+		for i := 0; i < 50; i++ {
+			points = append(points, Point{
+				X: b.Left + random.Int31n(b.Width()),
+				Y: b.Top + random.Int31n(b.Height()),
+			})
+		}
+
+		for _, p := range points {
+			dx, dy, err := NormalizeAbsolute(p, b)
+			if err != nil {
+				t.Fatalf("failed to normalize %+v in %+v - %s", p, b, err)
+			}
+
+			if dx < 0 || dx >= absoluteRange || dy < 0 || dy >= absoluteRange {
+				t.Fatalf("normalized %+v in %+v to out of range values %d,%d", p, b, dx, dy)
+			}
+
+			got := DenormalizeAbsolute(dx, dy, b)
+			if got != p {
+				t.Fatalf("round trip of %+v in %+v (normalized to %d,%d) landed on %+v",
+					p, b, dx, dy, got)
+			}
+		}
+	}
+}
+
+func TestNormalizeAbsoluteRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		p      Point
+		bounds Rect
+	}{
+		{name: "empty bounds", p: Point{}, bounds: Rect{}},
+		{name: "inverted bounds", p: Point{}, bounds: Rect{Left: 10, Right: 0, Bottom: 10}},
+		{name: "bounds too wide", p: Point{}, bounds: Rect{Right: absoluteRange + 1, Bottom: 10}},
+		{name: "right edge", p: Point{X: 100, Y: 0}, bounds: Rect{Right: 100, Bottom: 100}},
+		{name: "left of negative origin", p: Point{X: -101, Y: 0}, bounds: Rect{Left: -100, Right: 100, Bottom: 100}},
+	}
+
+	for _, test := range tests {
+		_, _, err := NormalizeAbsolute(test.p, test.bounds)
+		if err == nil {
+			t.Errorf("%s: expected an error for %+v in %+v", test.name, test.p, test.bounds)
+		}
+	}
+}
+
+func TestScreenLayoutVirtualDeskMouseInput(t *testing.T) {
+	layout := ScreenLayout{
+		Primary:       Rect{Right: 2560, Bottom: 1440},
+		VirtualScreen: Rect{Left: -1707, Top: -200, Right: 2560, Bottom: 1440},
+		Scale:         1.5,
+	}
+
+	// -1000,-100 in 144 DPI coordinates is -1500,-150 in physical pixels.
+	input, err := layout.VirtualDeskMouseInput(Point{X: -1000, Y: -100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expFlags := MouseEventFMove | MouseEventFAbsolute | MouseEventFVirtualDesk
+	if input.DwFlags != expFlags {
+		t.Fatalf("got flags 0x%X, expected 0x%X", input.DwFlags, expFlags)
+	}
+
+	exp := Point{X: -1500, Y: -150}
+	if got := DenormalizeAbsolute(input.Dx, input.Dy, layout.VirtualScreen); got != exp {
+		t.Fatalf("input lands on %+v, expected %+v", got, exp)
+	}
+
+	_, err = layout.AbsoluteMouseInput(Point{X: -1000, Y: -100})
+	if err == nil {
+		t.Fatal("expected an error for a point outside of the primary monitor")
+	}
+}
@@ -0,0 +1,289 @@
+package user32util
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// CursorInfo Flags values.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-cursorinfo
+const (
+	CursorShowing    uint32 = 0x00000001
+	CursorSuppressed uint32 = 0x00000002
+)
+
+// GetCursorPos returns the position of the mouse cursor in screen
+// coordinates. The coordinates are scaled according to the calling
+// thread's DPI awareness.
+//
+// From the Windows API documentation:
+//	Retrieves the position of the mouse cursor, in screen coordinates.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getcursorpos
+func GetCursorPos(user32 *User32DLL) (Point, error) {
+	var p Point
+
+	ret, _, err := user32.proc(procGetCursorPos).Call(uintptr(unsafe.Pointer(&p)))
+	if ret == 0 {
+		return Point{}, newCallError("get cursor position", getCursorPosName, err)
+	}
+
+	return p, nil
+}
+
+// GetPhysicalCursorPos returns the position of the mouse cursor in
+// physical coordinates, regardless of the calling thread's DPI awareness.
+//
+// From the Windows API documentation:
+//	Retrieves the position of the cursor in physical coordinates.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getphysicalcursorpos
+func GetPhysicalCursorPos(user32 *User32DLL) (Point, error) {
+	err := user32.proc(procGetPhysicalCursorPos).find()
+	if err != nil {
+		return Point{}, err
+	}
+
+	var p Point
+
+	ret, _, err := user32.proc(procGetPhysicalCursorPos).Call(uintptr(unsafe.Pointer(&p)))
+	if ret == 0 {
+		return Point{}, newCallError("get physical cursor position", getPhysCursorPosName, err)
+	}
+
+	return p, nil
+}
+
+// ClipCursor confines the cursor to the specified rectangle, in screen
+// coordinates. If rect is nil, the cursor is free to move anywhere on
+// the screen.
+//
+// Note that Windows releases the clip when the foreground window changes
+// or the workstation is locked. Refer to ClipTo for a helper that
+// restores the previous clip.
+//
+// From the Windows API documentation:
+//	Confines the cursor to a rectangular area on the screen. If a
+//	subsequent cursor position (set by the SetCursorPos function or the
+//	mouse) lies outside the rectangle, the system automatically adjusts
+//	the position to keep the cursor inside the rectangular area.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-clipcursor
+func ClipCursor(rect *Rect, user32 *User32DLL) error {
+	ret, _, err := user32.proc(procClipCursor).Call(uintptr(unsafe.Pointer(rect)))
+	if ret == 0 {
+		return newCallError("clip cursor", clipCursorName, err)
+	}
+
+	return nil
+}
+
+// GetClipCursor returns the rectangle that the cursor is confined to,
+// in screen coordinates. If the cursor is not confined, the rectangle
+// is the bounds of the virtual screen.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getclipcursor
+func GetClipCursor(user32 *User32DLL) (Rect, error) {
+	var rect Rect
+
+	ret, _, err := user32.proc(procGetClipCursor).Call(uintptr(unsafe.Pointer(&rect)))
+	if ret == 0 {
+		return Rect{}, newCallError("get cursor clip", getClipCursorName, err)
+	}
+
+	return rect, nil
+}
+
+// ShowCursor increments (if show is true) or decrements the cursor's
+// display counter, and returns the new value. The cursor is displayed
+// only if the counter is greater than or equal to zero.
+//
+// The counter belongs to the calling OS thread, and each call that hides
+// the cursor should be paired with a call that shows it on the same
+// thread. Because the Go runtime moves goroutines between threads,
+// callers must call runtime.LockOSThread before the first call and keep
+// the goroutine locked until the matching call has been made.
+//
+// The counter only affects the cursor while it is over a window owned by
+// the calling thread. A program without windows (e.g., a console program)
+// cannot hide the cursor over other applications' windows.
+//
+// Zero is returned if the procedure is unavailable. Use
+// User32DLL.Capabilities to check for it.
+//
+// From the Windows API documentation:
+//	This function sets an internal display counter that determines
+//	whether the cursor should be displayed. The cursor is displayed only
+//	if the display count is greater than or equal to 0. If a mouse is
+//	installed, the initial display count is 0. If no mouse is installed,
+//	the display count is -1.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-showcursor
+func ShowCursor(show bool, user32 *User32DLL) int32 {
+	var arg uintptr
+	if show {
+		arg = 1
+	}
+
+	ret, _, _ := user32.proc(procShowCursor).Call(arg)
+
+	return int32(ret)
+}
+
+// CursorInfo contains global cursor information.
+type CursorInfo struct {
+	// Flags is a combination of CursorShowing and CursorSuppressed,
+	// or zero if the cursor is hidden.
+	Flags uint32
+
+	// Cursor is a handle to the cursor's shape (an HCURSOR).
+	Cursor uintptr
+
+	// ScreenPos is the position of the cursor in screen coordinates.
+	ScreenPos Point
+}
+
+// Visible returns true if the cursor is showing.
+func (o CursorInfo) Visible() bool {
+	return o.Flags&CursorShowing != 0
+}
+
+// Suppressed returns true if the system is not drawing the cursor
+// because the user is providing input through touch or pen.
+func (o CursorInfo) Suppressed() bool {
+	return o.Flags&CursorSuppressed != 0
+}
+
+// cursorInfo is the CURSORINFO structure.
+type cursorInfo struct {
+	CbSize      uint32
+	Flags       uint32
+	HCursor     uintptr
+	PtScreenPos Point
+}
+
+// GetCursorInfo returns information about the global cursor.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getcursorinfo
+func GetCursorInfo(user32 *User32DLL) (CursorInfo, error) {
+	info := cursorInfo{}
+	info.CbSize = uint32(unsafe.Sizeof(info))
+
+	ret, _, err := user32.proc(procGetCursorInfo).Call(uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return CursorInfo{}, newCallError("get cursor info", getCursorInfoName, err)
+	}
+
+	return CursorInfo{
+		Flags:     info.Flags,
+		Cursor:    info.HCursor,
+		ScreenPos: info.PtScreenPos,
+	}, nil
+}
+
+// GetForegroundWindow returns a handle to the foreground window (the
+// window that the user is currently working with), or zero if there is
+// none (e.g., while the foreground window is changing). Zero is also
+// returned if the procedure is unavailable (refer to
+// User32DLL.Capabilities).
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getforegroundwindow
+func GetForegroundWindow(user32 *User32DLL) uintptr {
+	ret, _, _ := user32.proc(procGetForegroundWindow).Call()
+	return ret
+}
+
+// ClipTo confines the cursor to rect until the returned CursorClip is
+// closed, at which point the previous clip is restored.
+//
+// Windows releases the clip when the foreground window changes. Call
+// CursorClip.Reapply (e.g., periodically or when GetForegroundWindow
+// returns a different window) to keep the cursor confined. Refer to the
+// clipcursor example for more information.
+func ClipTo(rect Rect, user32 *User32DLL) (*CursorClip, error) {
+	previous, err := GetClipCursor(user32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ClipCursor(&rect, user32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CursorClip{
+		user32:   user32,
+		rect:     rect,
+		previous: previous,
+	}, nil
+}
+
+// CursorClip confines the cursor to a rectangle.
+//
+// Refer to ClipTo for more information.
+type CursorClip struct {
+	user32   *User32DLL
+	rect     Rect
+	previous Rect
+	mu       sync.Mutex
+	closed   bool
+}
+
+// Rect returns the rectangle that the cursor is confined to.
+func (o *CursorClip) Rect() Rect {
+	return o.rect
+}
+
+// Reapply confines the cursor to the clip's rectangle again.
+func (o *CursorClip) Reapply() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	return ClipCursor(&o.rect, o.user32)
+}
+
+// Close restores the clip that was in effect when ClipTo was called.
+// If the cursor was not confined, it is freed. Subsequent calls do
+// nothing.
+func (o *CursorClip) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	o.closed = true
+
+	if o.previous == virtualScreenRect(o.user32) {
+		return ClipCursor(nil, o.user32)
+	}
+
+	return ClipCursor(&o.previous, o.user32)
+}
+
+// virtualScreenRect returns the bounds of the virtual screen.
+func virtualScreenRect(user32 *User32DLL) Rect {
+	left := GetSystemMetrics(SMXVirtualScreen, user32)
+	top := GetSystemMetrics(SMYVirtualScreen, user32)
+
+	return Rect{
+		Left:   left,
+		Top:    top,
+		Right:  left + GetSystemMetrics(SMCxVirtualScreen, user32),
+		Bottom: top + GetSystemMetrics(SMCyVirtualScreen, user32),
+	}
+}
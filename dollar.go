@@ -0,0 +1,252 @@
+package user32util
+
+import (
+	"math"
+)
+
+// Parameters of the $1 unistroke recognizer.
+//
+// Refer to the following paper for more information:
+// https://depts.washington.edu/acelab/proj/dollar/index.html
+const (
+	dollarNumPoints      = 64
+	dollarSquareSize     = 250.0
+	dollarAngleRange     = 45 * math.Pi / 180
+	dollarAnglePrecision = 2 * math.Pi / 180
+)
+
+var (
+	dollarHalfDiagonal = 0.5 * math.Sqrt(2*dollarSquareSize*dollarSquareSize)
+	dollarPhi          = 0.5 * (math.Sqrt(5) - 1)
+)
+
+// GestureTemplate is a named stroke that a GestureRecognizer matches
+// drawn strokes against.
+type GestureTemplate struct {
+	Name   string
+	Points []Point
+}
+
+type vec2 struct {
+	x float64
+	y float64
+}
+
+type dollarTemplate struct {
+	name   string
+	points []vec2
+}
+
+func newDollarTemplate(template GestureTemplate) dollarTemplate {
+	return dollarTemplate{
+		name:   template.Name,
+		points: dollarNormalize(template.Points),
+	}
+}
+
+// dollarRecognize returns the name and score (0 through 1) of the template
+// that best matches points. The name is empty if there are no templates
+// or too few points.
+func dollarRecognize(points []Point, templates []dollarTemplate) (string, float64) {
+	if len(templates) == 0 || len(points) < 2 {
+		return "", 0
+	}
+
+	candidate := dollarNormalize(points)
+
+	best := math.Inf(1)
+	var bestName string
+
+	for _, template := range templates {
+		d := dollarDistanceAtBestAngle(candidate, template.points,
+			-dollarAngleRange, dollarAngleRange, dollarAnglePrecision)
+		if d < best {
+			best = d
+			bestName = template.name
+		}
+	}
+
+	return bestName, 1 - best/dollarHalfDiagonal
+}
+
+// dollarNormalize resamples, rotates, scales and translates a stroke so
+// that it can be compared against other normalized strokes.
+func dollarNormalize(points []Point) []vec2 {
+	raw := make([]vec2, len(points))
+	for i, p := range points {
+		raw[i] = vec2{x: float64(p.X), y: float64(p.Y)}
+	}
+
+	resampled := dollarResample(raw, dollarNumPoints)
+	rotated := dollarRotateBy(resampled, -dollarIndicativeAngle(resampled))
+	scaled := dollarScaleTo(rotated, dollarSquareSize)
+
+	return dollarTranslateTo(scaled, vec2{})
+}
+
+func dollarResample(points []vec2, n int) []vec2 {
+	if len(points) == 0 {
+		return nil
+	}
+
+	interval := dollarPathLength(points) / float64(n-1)
+	resampled := []vec2{points[0]}
+
+	if interval == 0 {
+		for len(resampled) < n {
+			resampled = append(resampled, points[0])
+		}
+		return resampled
+	}
+
+	var accumulated float64
+	pts := append([]vec2(nil), points...)
+
+	for i := 1; i < len(pts); i++ {
+		d := vecDistance(pts[i-1], pts[i])
+		if accumulated+d >= interval {
+			t := (interval - accumulated) / d
+			q := vec2{
+				x: pts[i-1].x + t*(pts[i].x-pts[i-1].x),
+				y: pts[i-1].y + t*(pts[i].y-pts[i-1].y),
+			}
+			resampled = append(resampled, q)
+
+			// q becomes the start of the next segment.
+			pts = append(pts[:i], append([]vec2{q}, pts[i:]...)...)
+			accumulated = 0
+		} else {
+			accumulated += d
+		}
+	}
+
+	// Rounding errors may leave the stroke one point short.
+	for len(resampled) < n {
+		resampled = append(resampled, points[len(points)-1])
+	}
+
+	return resampled[:n]
+}
+
+func dollarIndicativeAngle(points []vec2) float64 {
+	c := vecCentroid(points)
+	return math.Atan2(c.y-points[0].y, c.x-points[0].x)
+}
+
+func dollarRotateBy(points []vec2, radians float64) []vec2 {
+	c := vecCentroid(points)
+	cos := math.Cos(radians)
+	sin := math.Sin(radians)
+
+	rotated := make([]vec2, len(points))
+	for i, p := range points {
+		rotated[i] = vec2{
+			x: (p.x-c.x)*cos - (p.y-c.y)*sin + c.x,
+			y: (p.x-c.x)*sin + (p.y-c.y)*cos + c.y,
+		}
+	}
+
+	return rotated
+}
+
+func dollarScaleTo(points []vec2, size float64) []vec2 {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+		minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+	}
+
+	// Avoid dividing by zero for perfectly straight strokes.
+	width := math.Max(maxX-minX, 1)
+	height := math.Max(maxY-minY, 1)
+
+	scaled := make([]vec2, len(points))
+	for i, p := range points {
+		scaled[i] = vec2{
+			x: p.x * size / width,
+			y: p.y * size / height,
+		}
+	}
+
+	return scaled
+}
+
+func dollarTranslateTo(points []vec2, to vec2) []vec2 {
+	c := vecCentroid(points)
+
+	translated := make([]vec2, len(points))
+	for i, p := range points {
+		translated[i] = vec2{
+			x: p.x + to.x - c.x,
+			y: p.y + to.y - c.y,
+		}
+	}
+
+	return translated
+}
+
+// dollarDistanceAtBestAngle uses a golden section search to find the
+// rotation of a that is closest to b, returning the resulting distance.
+func dollarDistanceAtBestAngle(a []vec2, b []vec2, from float64, to float64, threshold float64) float64 {
+	x1 := dollarPhi*from + (1-dollarPhi)*to
+	f1 := dollarPathDistance(dollarRotateBy(a, x1), b)
+	x2 := (1-dollarPhi)*from + dollarPhi*to
+	f2 := dollarPathDistance(dollarRotateBy(a, x2), b)
+
+	for math.Abs(to-from) > threshold {
+		if f1 < f2 {
+			to = x2
+			x2 = x1
+			f2 = f1
+			x1 = dollarPhi*from + (1-dollarPhi)*to
+			f1 = dollarPathDistance(dollarRotateBy(a, x1), b)
+		} else {
+			from = x1
+			x1 = x2
+			f1 = f2
+			x2 = (1-dollarPhi)*from + dollarPhi*to
+			f2 = dollarPathDistance(dollarRotateBy(a, x2), b)
+		}
+	}
+
+	return math.Min(f1, f2)
+}
+
+// dollarPathDistance returns the average distance between the
+// corresponding points of two equally sized strokes.
+func dollarPathDistance(a []vec2, b []vec2) float64 {
+	var d float64
+	for i := range a {
+		d += vecDistance(a[i], b[i])
+	}
+
+	return d / float64(len(a))
+}
+
+func dollarPathLength(points []vec2) float64 {
+	var d float64
+	for i := 1; i < len(points); i++ {
+		d += vecDistance(points[i-1], points[i])
+	}
+
+	return d
+}
+
+func vecCentroid(points []vec2) vec2 {
+	var c vec2
+	for _, p := range points {
+		c.x += p.x
+		c.y += p.y
+	}
+
+	c.x /= float64(len(points))
+	c.y /= float64(len(points))
+
+	return c
+}
+
+func vecDistance(a vec2, b vec2) float64 {
+	return math.Hypot(b.x-a.x, b.y-a.y)
+}
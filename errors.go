@@ -0,0 +1,134 @@
+package user32util
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// Sentinel errors that a CallError matches when using errors.Is.
+var (
+	// ErrAccessDenied is matched by ERROR_ACCESS_DENIED. It commonly
+	// occurs when User Interface Privilege Isolation (UIPI) prevents
+	// a process from interacting with a higher integrity process.
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrHookNeedsHMod is matched by ERROR_HOOK_NEEDS_HMOD.
+	ErrHookNeedsHMod = errors.New("hook procedure requires a module handle")
+
+	// ErrInvalidParameter is matched by ERROR_INVALID_PARAMETER.
+	ErrInvalidParameter = errors.New("invalid parameter")
+)
+
+// errorHookNeedsHMod is not defined by golang.org/x/sys/windows.
+const errorHookNeedsHMod windows.Errno = 1428
+
+// CallError describes a failed call to a Windows API procedure.
+//
+// Use errors.Is to compare a CallError against the sentinel errors
+// (e.g., ErrAccessDenied), a specific windows.Errno, or
+// ErrProcUnavailable if the procedure could not be called.
+type CallError struct {
+	// Op describes the operation that failed (e.g., "send input").
+	Op string
+
+	// Proc is the name of the procedure that failed.
+	Proc string
+
+	// Errno is the value of GetLastError after the call. It is zero
+	// if the procedure failed without setting an error code, or if it
+	// could not be called.
+	Errno windows.Errno
+
+	// Err is the error that prevented the procedure from being called
+	// (e.g., an error wrapping ErrProcUnavailable), or nil if it was
+	// called.
+	Err error
+}
+
+// newCallError returns a *CallError for err, which is the error returned
+// by windows.Proc.Call.
+func newCallError(op string, proc string, err error) *CallError {
+	callErr := &CallError{
+		Op:   op,
+		Proc: proc,
+	}
+
+	var errno windows.Errno
+	if errors.As(err, &errno) {
+		callErr.Errno = errno
+	} else {
+		callErr.Err = err
+	}
+
+	return callErr
+}
+
+func (o *CallError) Error() string {
+	if o.Err != nil {
+		return fmt.Sprintf("failed to %s - %s", o.Op, o.Err.Error())
+	}
+
+	if o.Errno == 0 {
+		return fmt.Sprintf("failed to %s - %s failed without an error code", o.Op, o.Proc)
+	}
+
+	return fmt.Sprintf("failed to %s - %s: %s", o.Op, o.Proc, o.Errno.Error())
+}
+
+// Unwrap returns the error's Err if it is non-nil. Otherwise, it returns
+// the error's Errno, or nil if it is zero.
+func (o *CallError) Unwrap() error {
+	if o.Err != nil {
+		return o.Err
+	}
+
+	if o.Errno == 0 {
+		return nil
+	}
+
+	return o.Errno
+}
+
+// Is returns true if target is the sentinel error that corresponds
+// to the error's Errno.
+func (o *CallError) Is(target error) bool {
+	switch target {
+	case ErrAccessDenied:
+		return o.Errno == windows.ERROR_ACCESS_DENIED
+	case ErrHookNeedsHMod:
+		return o.Errno == errorHookNeedsHMod
+	case ErrInvalidParameter:
+		return o.Errno == windows.ERROR_INVALID_PARAMETER
+	default:
+		return false
+	}
+}
+
+// SendInputError is returned when SendInput inserts fewer inputs into
+// the input stream than were requested.
+//
+// From the Windows API documentation:
+//	This function fails when it is blocked by UIPI. Note that neither
+//	GetLastError nor the return value will indicate the failure was
+//	caused by UIPI blocking.
+type SendInputError struct {
+	// Sent is the number of inputs that were inserted.
+	Sent uint
+
+	// Requested is the number of inputs that were passed to SendInput.
+	Requested uint
+
+	// Err describes why SendInput failed.
+	Err *CallError
+}
+
+func (o *SendInputError) Error() string {
+	return fmt.Sprintf("sent %d of %d inputs - %s", o.Sent, o.Requested, o.Err.Error())
+}
+
+// Unwrap returns the underlying *CallError.
+func (o *SendInputError) Unwrap() error {
+	return o.Err
+}
@@ -0,0 +1,111 @@
+package user32util
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestCallErrorErrno(t *testing.T) {
+	err := error(newCallError("send input", sendInputName, windows.ERROR_ACCESS_DENIED))
+
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("expected %v to match ErrAccessDenied", err)
+	}
+
+	if !errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		t.Fatalf("expected %v to match the errno", err)
+	}
+
+	if errors.Is(err, ErrInvalidParameter) || errors.Is(err, ErrProcUnavailable) {
+		t.Fatalf("expected %v to only match ErrAccessDenied", err)
+	}
+
+	if zero := newCallError("send input", sendInputName, windows.Errno(0)); zero.Unwrap() != nil || zero.Err != nil {
+		t.Fatalf("expected a zero errno not to be wrapped, got %+v", zero)
+	}
+}
+
+func TestCallErrorProcUnavailable(t *testing.T) {
+	user32 := newUnavailableUser32()
+
+	calls := map[string]func() error{
+		"GetCursorPos": func() error {
+			_, err := GetCursorPos(user32)
+			return err
+		},
+		"ClipCursor": func() error {
+			return ClipCursor(&Rect{}, user32)
+		},
+		"GetClipCursor": func() error {
+			_, err := GetClipCursor(user32)
+			return err
+		},
+		"GetCursorInfo": func() error {
+			_, err := GetCursorInfo(user32)
+			return err
+		},
+		"GetKeyboardState": func() error {
+			_, err := GetKeyboardState(user32)
+			return err
+		},
+		"EnumDisplayMonitors": func() error {
+			_, err := EnumDisplayMonitors(user32)
+			return err
+		},
+		"GetMonitorInfo": func() error {
+			_, err := GetMonitorInfo(1, user32)
+			return err
+		},
+		"MonitorFromPoint": func() error {
+			_, err := MonitorFromPoint(Point{}, MonitorDefaultToNull, user32)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		err := call()
+
+		if !errors.Is(err, ErrProcUnavailable) {
+			t.Fatalf("%s: expected %v to wrap ErrProcUnavailable", name, err)
+		}
+
+		var callErr *CallError
+		if !errors.As(err, &callErr) || callErr.Errno != 0 || callErr.Err == nil {
+			t.Fatalf("%s: expected a *CallError without an errno, got %#v", name, err)
+		}
+
+		if errors.Is(err, ErrAccessDenied) {
+			t.Fatalf("%s: expected %v not to match ErrAccessDenied", name, err)
+		}
+	}
+}
+
+func TestZeroValuesWhenProcUnavailable(t *testing.T) {
+	user32 := newUnavailableUser32()
+
+	if got := GetSystemMetrics(SMCxDrag, user32); got != 0 {
+		t.Fatalf("GetSystemMetrics returned %d", got)
+	}
+
+	if got := GetDoubleClickTime(user32); got != 0 {
+		t.Fatalf("GetDoubleClickTime returned %s", got)
+	}
+
+	if GetAsyncKeyState(VKShift, user32) {
+		t.Fatal("GetAsyncKeyState returned true")
+	}
+
+	if down, toggled := GetKeyState(VKCapital, user32); down || toggled {
+		t.Fatalf("GetKeyState returned %t, %t", down, toggled)
+	}
+
+	if got := ShowCursor(true, user32); got != 0 {
+		t.Fatalf("ShowCursor returned %d", got)
+	}
+
+	if got := GetForegroundWindow(user32); got != 0 {
+		t.Fatalf("GetForegroundWindow returned %#x", got)
+	}
+}
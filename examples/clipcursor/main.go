@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/stephen-fox/user32util"
+)
+
+func main() {
+	monitorNum := flag.Int("monitor", 0, "1-based monitor number to confine the cursor to (defaults to the primary monitor)")
+	interval := flag.Duration("interval", 50*time.Millisecond, "time.Duration between foreground window checks")
+
+	flag.Parse()
+
+	err := run(*monitorNum, *interval)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func run(monitorNum int, interval time.Duration) error {
+	user32, err := user32util.Acquire()
+	if err != nil {
+		return fmt.Errorf("failed to load user32.dll - %s", err)
+	}
+	defer user32.Release()
+
+	monitor, err := findMonitor(monitorNum, user32)
+	if err != nil {
+		return err
+	}
+
+	clip, err := user32util.ClipTo(monitor.Bounds, user32)
+	if err != nil {
+		return fmt.Errorf("failed to clip cursor - %s", err)
+	}
+	defer clip.Close()
+
+	log.Printf("confining cursor to %+v - press Ctrl+C to stop", clip.Rect())
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Windows releases the clip whenever the foreground window changes,
+	// so reapply it each time a different window comes to the front.
+	foreground := user32util.GetForegroundWindow(user32)
+
+	for {
+		select {
+		case <-interrupts:
+			return nil
+		case <-ticker.C:
+		}
+
+		current := user32util.GetForegroundWindow(user32)
+		if current == foreground {
+			continue
+		}
+
+		foreground = current
+
+		err := clip.Reapply()
+		if err != nil {
+			return fmt.Errorf("failed to reapply clip - %s", err)
+		}
+
+		log.Printf("foreground window changed to 0x%X - reapplied clip", current)
+	}
+}
+
+func findMonitor(num int, user32 *user32util.User32DLL) (user32util.Monitor, error) {
+	monitors, err := user32util.EnumDisplayMonitors(user32)
+	if err != nil {
+		return user32util.Monitor{}, fmt.Errorf("failed to enumerate monitors - %s", err)
+	}
+
+	if num == 0 {
+		for _, monitor := range monitors {
+			if monitor.Primary {
+				return monitor, nil
+			}
+		}
+
+		return user32util.Monitor{}, fmt.Errorf("failed to find the primary monitor")
+	}
+
+	if num < 1 || num > len(monitors) {
+		return user32util.Monitor{}, fmt.Errorf("monitor %d was specified, but there are %d monitors",
+			num, len(monitors))
+	}
+
+	return monitors[num-1], nil
+}
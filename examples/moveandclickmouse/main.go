@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -17,88 +19,118 @@ func main() {
 
 	flag.Parse()
 
-	dll, err := user32util.LoadUser32DLL()
+	// log.Fatal exits without running deferred calls, so the work is
+	// done in run, which releases the dll before main exits.
+	err := run(*sleep)
 	if err != nil {
-		log.Fatalf("failed to load user32 dll - %s", err)
+		log.Fatalln(err)
 	}
+}
+
+func run(sleep time.Duration) error {
+	dll, err := user32util.Acquire()
+	if err != nil {
+		return fmt.Errorf("failed to load user32 dll - %s", err)
+	}
+	defer dll.Release()
 
 	if flag.Arg(0) == "print" {
 		log.Println("will show mouse movement")
-		getPoints(dll)
-	} else {
-		if flag.NArg() == 0 {
-			log.Fatalf("please specify at least one coordinate pair")
-		}
+		return getPoints(dll)
+	}
 
-		points := make([]user32util.Point, flag.NArg())
-		for i, coordStr := range flag.Args() {
-			coordParts := strings.Split(coordStr, ",")
-			if len(coordParts) != 2 {
-				log.Fatalf("argument number %d is not in the format x,y", i)
-			}
+	if flag.NArg() == 0 {
+		return fmt.Errorf("please specify at least one coordinate pair")
+	}
 
-			x, err := strconv.Atoi(coordParts[0])
+	points := make([]user32util.Point, flag.NArg())
+	for i, coordStr := range flag.Args() {
+		if strings.HasPrefix(coordStr, monitorArgPrefix) {
+			points[i], err = monitorCenter(i, strings.TrimPrefix(coordStr, monitorArgPrefix), dll)
 			if err != nil {
-				log.Fatalf("failed to parse x coord of argument %d (%s) - %s", i, coordParts[0], err)
+				return err
 			}
+			continue
+		}
 
-			y, err := strconv.Atoi(coordParts[1])
-			if err != nil {
-				log.Fatalf("failed to parse y coord of argument %d (%s) - %s", i, coordParts[1], err)
-			}
+		coordParts := strings.Split(coordStr, ",")
+		if len(coordParts) != 2 {
+			return fmt.Errorf("argument number %d is not in the format x,y", i)
+		}
 
-			points[i] = user32util.Point{
-				X: int32(x),
-				Y: int32(y),
-			}
+		x, err := strconv.Atoi(coordParts[0])
+		if err != nil {
+			return fmt.Errorf("failed to parse x coord of argument %d (%s) - %s", i, coordParts[0], err)
 		}
 
-		log.Printf("clicking between %+v", points)
-		clickBetween(points, *sleep, dll)
+		y, err := strconv.Atoi(coordParts[1])
+		if err != nil {
+			return fmt.Errorf("failed to parse y coord of argument %d (%s) - %s", i, coordParts[1], err)
+		}
+
+		points[i] = user32util.Point{
+			X: int32(x),
+			Y: int32(y),
+		}
 	}
+
+	log.Printf("clicking between %+v", points)
+	return clickBetween(points, sleep, dll)
 }
 
-func getPoints(dll *user32util.User32DLL) {
+const monitorArgPrefix = "monitor:"
+
+// monitorCenter returns the center of the 1-based monitor number
+// specified in numStr.
+func monitorCenter(argIndex int, numStr string, dll *user32util.User32DLL) (user32util.Point, error) {
+	num, err := strconv.Atoi(numStr)
+	if err != nil {
+		return user32util.Point{}, fmt.Errorf("failed to parse monitor number of argument %d (%s) - %s", argIndex, numStr, err)
+	}
+
+	monitors, err := user32util.EnumDisplayMonitors(dll)
+	if err != nil {
+		return user32util.Point{}, fmt.Errorf("failed to enumerate monitors - %s", err)
+	}
+
+	if num < 1 || num > len(monitors) {
+		return user32util.Point{}, fmt.Errorf("argument number %d specifies monitor %d, but there are %d monitors",
+			argIndex, num, len(monitors))
+	}
+
+	return monitors[num-1].Center(), nil
+}
+
+func getPoints(dll *user32util.User32DLL) error {
 	listener, err := user32util.NewLowLevelMouseListener(func(event user32util.LowLevelMouseEvent) {
 		log.Printf("mouse x,y: %d,%d", event.Struct.Point.X, event.Struct.Point.Y)
 	}, dll)
 	if err != nil {
-		log.Fatalf("failed to start listner - %s", err)
+		return fmt.Errorf("failed to start listner - %s", err)
 	}
 
 	interrupts := make(chan os.Signal)
 	signal.Notify(interrupts, os.Interrupt)
 	select {
 	case <-interrupts:
-		listener.Release()
+		return listener.Release()
 	case err := <-listener.OnDone():
-		log.Fatalln("listener exited - err is:", err)
+		return fmt.Errorf("listener exited - err is: %v", err)
 	}
 }
 
-func clickBetween(sequentialClicks []user32util.Point, sleep time.Duration, dll *user32util.User32DLL) {
+func clickBetween(sequentialClicks []user32util.Point, sleep time.Duration, dll *user32util.User32DLL) error {
+	backend := user32util.NewUser32Backend(dll)
+
 	for {
 		for _, point := range sequentialClicks {
-			log.Printf("moving to point %+v", point)
-			_, err := user32util.SetCursorPos(point.X, point.Y, dll)
-			if err != nil {
-				log.Fatalf("failed to send mouse down input - %s", err)
-			}
-
-			log.Println("clicking")
-			err = user32util.SendMouseInput(user32util.MouseInput{
-				DwFlags: user32util.MouseEventFLeftDown,
-			}, dll)
-			if err != nil {
-				log.Fatalf("failed to send mouse down input - %s", err)
-			}
-
-			log.Println("unclick")
-			err = user32util.SendMouseInput(user32util.MouseInput{
-				DwFlags: user32util.MouseEventFLeftUp,
-			}, dll)
+			log.Printf("clicking on point %+v", point)
+			err := user32util.NewSequence().
+				MoveTo(point.X, point.Y).
+				Click(user32util.MouseButtonLeft).
+				Run(context.Background(), backend)
 			if err != nil {
-				log.Fatalf("failed to send mouse up input - %s", err)
+				return fmt.Errorf("failed to click on point %+v - %s", point, err)
 			}
 
 			log.Printf("sleeping for %s", sleep.String())
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/stephen-fox/user32util"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "Log library debug messages to stderr")
+	flag.Parse()
+
+	var config user32util.Config
+	if *verbose {
+		config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		}))
+	}
+
+	user32, err := user32util.AcquireWithConfig(config)
+	if err != nil {
+		log.Fatalf("failed to load user32.dll - %s", err.Error())
+	}
+	defer user32.Release()
+
+	fn := func(event user32util.Event) {
+		switch e := event.(type) {
+		case user32util.KeyEvent:
+			log.Printf("%d: key 0x%X action %d", e.Seq, e.Struct.VkCode, e.KeyboardButtonAction())
+		case user32util.MouseEvent:
+			log.Printf("%d: mouse %+v action 0x%X", e.Seq, e.Struct.Point, e.MouseButtonAction())
+		}
+	}
+
+	listener, err := user32util.NewInputListener(fn, user32)
+	if err != nil {
+		log.Fatalf("failed to create input listener - %s", err.Error())
+	}
+
+	log.Println("now listening for keyboard and mouse events - press Ctrl+C to stop")
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	select {
+	case err := <-listener.OnDone():
+		log.Fatalf("input listener stopped unexpectedly - %v", err)
+	case <-interrupts:
+	}
+
+	listener.Release()
+}
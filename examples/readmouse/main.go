@@ -9,10 +9,11 @@ import (
 )
 
 func main() {
-	user32, err := user32util.LoadUser32DLL()
+	user32, err := user32util.Acquire()
 	if err != nil {
 		log.Fatalf("failed to load user32.dll - %s", err.Error())
 	}
+	defer user32.Release()
 
 	fn := func(event user32util.LowLevelMouseEvent) {
 		log.Printf("mouse event: %+v", event.Struct.Point)
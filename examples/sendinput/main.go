@@ -14,10 +14,11 @@ func main() {
 
 	flag.Parse()
 
-	user32, err := user32util.LoadUser32DLL()
+	user32, err := user32util.Acquire()
 	if err != nil {
 		log.Fatalf("failed to load user32.dll - %s", err.Error())
 	}
+	defer user32.Release()
 
 	if *mouse {
 		for {
@@ -0,0 +1,246 @@
+package user32util
+
+// Filter is a predicate over listener events. Filters are built using
+// the functions in this file and combined using And, Or and Not:
+//	filter := user32util.And(
+//		user32util.NotInjected(),
+//		user32util.Or(
+//			user32util.KeyIs(user32util.VKF1, user32util.VKF2),
+//			user32util.ButtonIs(user32util.MouseButtonLeft)))
+//
+// Filters receive events by value and do not allocate memory, which makes
+// them suitable for discarding high-frequency events (e.g., WMMouseMove)
+// on the hook's thread.
+//
+// The zero value matches every event.
+type Filter struct {
+	keyboard func(event LowLevelKeyboardEvent) bool
+	mouse    func(event LowLevelMouseEvent) bool
+
+	// modifiers is true if the filter uses the events' Modifiers
+	// field. Listeners that query modifiers using GetAsyncModifiers
+	// only do so before filtering if it is true.
+	modifiers bool
+}
+
+// KeyboardFilter returns a Filter that matches keyboard events for which
+// fn returns true. It never matches mouse events.
+func KeyboardFilter(fn func(event LowLevelKeyboardEvent) bool) Filter {
+	return Filter{
+		keyboard: fn,
+		mouse:    matchNoMouse,
+	}
+}
+
+// MouseFilter returns a Filter that matches mouse events for which fn
+// returns true. It never matches keyboard events.
+//
+// Listeners that only receive mouse events query the events' modifiers
+// after filtering, meaning the Modifiers field is zero when fn is called.
+// Combine fn with WithModifiers to filter on modifiers.
+func MouseFilter(fn func(event LowLevelMouseEvent) bool) Filter {
+	return Filter{
+		keyboard: matchNoKeyboard,
+		mouse:    fn,
+	}
+}
+
+// MatchKeyboard returns true if the filter matches a keyboard event.
+func (o Filter) MatchKeyboard(event LowLevelKeyboardEvent) bool {
+	return o.keyboard == nil || o.keyboard(event)
+}
+
+// MatchMouse returns true if the filter matches a mouse event.
+func (o Filter) MatchMouse(event LowLevelMouseEvent) bool {
+	return o.mouse == nil || o.mouse(event)
+}
+
+// Match returns true if the filter matches an Event.
+func (o Filter) Match(event Event) bool {
+	switch e := event.(type) {
+	case KeyEvent:
+		return o.MatchKeyboard(e.LowLevelKeyboardEvent)
+	case MouseEvent:
+		return o.MatchMouse(e.LowLevelMouseEvent)
+	}
+
+	return false
+}
+
+// KeyIs matches keyboard events for any of the specified keys.
+func KeyIs(vks ...VirtualKey) Filter {
+	return KeyboardFilter(func(event LowLevelKeyboardEvent) bool {
+		for _, vk := range vks {
+			if VirtualKey(event.Struct.VkCode) == vk {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ActionIs matches keyboard events with any of the specified actions.
+func ActionIs(actions ...KeyboardButtonAction) Filter {
+	return KeyboardFilter(func(event LowLevelKeyboardEvent) bool {
+		for _, action := range actions {
+			if event.KeyboardButtonAction() == action {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// MouseActionIs matches mouse events with any of the specified actions.
+func MouseActionIs(actions ...MouseButtonAction) Filter {
+	return MouseFilter(func(event LowLevelMouseEvent) bool {
+		for _, action := range actions {
+			if event.MouseButtonAction() == action {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// ButtonIs matches mouse events that press or release any of the
+// specified buttons.
+func ButtonIs(buttons ...MouseButton) Filter {
+	return MouseFilter(func(event LowLevelMouseEvent) bool {
+		button, _, ok := event.Button()
+		if !ok {
+			return false
+		}
+
+		for _, b := range buttons {
+			if button == b {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// InRect matches mouse events that occur within the specified rectangle.
+func InRect(rect Rect) Filter {
+	return MouseFilter(func(event LowLevelMouseEvent) bool {
+		return rect.Contains(event.Struct.Point)
+	})
+}
+
+// NotInjected matches keyboard and mouse events that were not injected
+// by any process.
+func NotInjected() Filter {
+	return Filter{
+		keyboard: func(event LowLevelKeyboardEvent) bool {
+			return !event.IsInjected()
+		},
+		mouse: func(event LowLevelMouseEvent) bool {
+			return !event.IsInjected()
+		},
+	}
+}
+
+// WithModifiers matches keyboard and mouse events that occur while all
+// of the specified modifiers are held. Other modifiers may also be held.
+// It also works with listeners that only receive mouse events, whose
+// modifiers are queried using GetAsyncModifiers.
+//
+// Refer to the Modifiers field of LowLevelKeyboardEvent and
+// LowLevelMouseEvent for more information.
+func WithModifiers(mods Modifiers) Filter {
+	return Filter{
+		modifiers: true,
+		keyboard: func(event LowLevelKeyboardEvent) bool {
+			return event.Modifiers.Has(mods)
+		},
+		mouse: func(event LowLevelMouseEvent) bool {
+			return event.Modifiers.Has(mods)
+		},
+	}
+}
+
+// And matches events that match all of the specified filters.
+func And(filters ...Filter) Filter {
+	return Filter{
+		modifiers: anyUsesModifiers(filters),
+		keyboard: func(event LowLevelKeyboardEvent) bool {
+			for _, f := range filters {
+				if !f.MatchKeyboard(event) {
+					return false
+				}
+			}
+
+			return true
+		},
+		mouse: func(event LowLevelMouseEvent) bool {
+			for _, f := range filters {
+				if !f.MatchMouse(event) {
+					return false
+				}
+			}
+
+			return true
+		},
+	}
+}
+
+// Or matches events that match any of the specified filters.
+func Or(filters ...Filter) Filter {
+	return Filter{
+		modifiers: anyUsesModifiers(filters),
+		keyboard: func(event LowLevelKeyboardEvent) bool {
+			for _, f := range filters {
+				if f.MatchKeyboard(event) {
+					return true
+				}
+			}
+
+			return false
+		},
+		mouse: func(event LowLevelMouseEvent) bool {
+			for _, f := range filters {
+				if f.MatchMouse(event) {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+}
+
+// Not matches events that do not match the specified filter.
+func Not(filter Filter) Filter {
+	return Filter{
+		modifiers: filter.modifiers,
+		keyboard: func(event LowLevelKeyboardEvent) bool {
+			return !filter.MatchKeyboard(event)
+		},
+		mouse: func(event LowLevelMouseEvent) bool {
+			return !filter.MatchMouse(event)
+		},
+	}
+}
+
+func anyUsesModifiers(filters []Filter) bool {
+	for _, f := range filters {
+		if f.modifiers {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchNoKeyboard(LowLevelKeyboardEvent) bool {
+	return false
+}
+
+func matchNoMouse(LowLevelMouseEvent) bool {
+	return false
+}
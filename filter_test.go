@@ -0,0 +1,181 @@
+package user32util
+
+import (
+	"testing"
+)
+
+func filterKeyEvent(vk VirtualKey, mods Modifiers) LowLevelKeyboardEvent {
+	return LowLevelKeyboardEvent{
+		WParam:    uintptr(WMKeyDown),
+		Struct:    &KbdllHookStruct{VkCode: uint32(vk)},
+		Modifiers: mods,
+	}
+}
+
+func filterMouseEvent(action MouseButtonAction, mods Modifiers) LowLevelMouseEvent {
+	return LowLevelMouseEvent{
+		WParam:    uintptr(action),
+		Struct:    &MsllHookStruct{},
+		Modifiers: mods,
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	keyA := filterKeyEvent(VirtualKey('A'), 0)
+	ctrlA := filterKeyEvent(VirtualKey('A'), ModControl)
+	ctrlShiftB := filterKeyEvent(VirtualKey('B'), ModControl|ModShift)
+	leftDown := filterMouseEvent(WMLButtonDown, 0)
+	ctrlLeftUp := filterMouseEvent(WMLButtonUp, ModControl)
+	rightDown := filterMouseEvent(WMRButtonDown, 0)
+	move := filterMouseEvent(WMMouseMove, ModControl)
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		keyboard []LowLevelKeyboardEvent
+		mouse    []LowLevelMouseEvent
+		exp      []bool
+	}{
+		{
+			name:     "zero value",
+			filter:   Filter{},
+			keyboard: []LowLevelKeyboardEvent{keyA},
+			mouse:    []LowLevelMouseEvent{move},
+			exp:      []bool{true, true},
+		},
+		{
+			name:     "KeyIs",
+			filter:   KeyIs(VirtualKey('B'), VirtualKey('C')),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{leftDown},
+			exp:      []bool{false, true, false},
+		},
+		{
+			name:     "ButtonIs",
+			filter:   ButtonIs(MouseButtonLeft),
+			keyboard: []LowLevelKeyboardEvent{keyA},
+			mouse:    []LowLevelMouseEvent{leftDown, ctrlLeftUp, rightDown, move},
+			exp:      []bool{false, true, true, false, false},
+		},
+		{
+			name:     "WithModifiers",
+			filter:   WithModifiers(ModControl),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{leftDown, ctrlLeftUp},
+			exp:      []bool{false, true, true, false, true},
+		},
+		{
+			name:     "WithModifiers requires all modifiers",
+			filter:   WithModifiers(ModControl | ModShift),
+			keyboard: []LowLevelKeyboardEvent{ctrlA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{ctrlLeftUp},
+			exp:      []bool{false, true, false},
+		},
+		{
+			name:     "And",
+			filter:   And(KeyIs(VirtualKey('A')), WithModifiers(ModControl)),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{ctrlLeftUp},
+			exp:      []bool{false, true, false, false},
+		},
+		{
+			name:     "empty And",
+			filter:   And(),
+			keyboard: []LowLevelKeyboardEvent{keyA},
+			mouse:    []LowLevelMouseEvent{move},
+			exp:      []bool{true, true},
+		},
+		{
+			name:     "Or",
+			filter:   Or(KeyIs(VirtualKey('A')), ButtonIs(MouseButtonRight)),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{leftDown, rightDown},
+			exp:      []bool{true, false, false, true},
+		},
+		{
+			name:     "empty Or",
+			filter:   Or(),
+			keyboard: []LowLevelKeyboardEvent{keyA},
+			mouse:    []LowLevelMouseEvent{move},
+			exp:      []bool{false, false},
+		},
+		{
+			name:     "Not",
+			filter:   Not(KeyIs(VirtualKey('A'))),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{move},
+			exp:      []bool{false, true, true},
+		},
+		{
+			name: "nested",
+			filter: And(
+				Not(WithModifiers(ModShift)),
+				Or(KeyIs(VirtualKey('A'), VirtualKey('B')), ButtonIs(MouseButtonLeft))),
+			keyboard: []LowLevelKeyboardEvent{keyA, ctrlA, ctrlShiftB},
+			mouse:    []LowLevelMouseEvent{leftDown, rightDown, move},
+			exp:      []bool{true, true, false, true, false, false},
+		},
+	}
+
+	for _, test := range tests {
+		var got []bool
+
+		for _, event := range test.keyboard {
+			got = append(got, test.filter.MatchKeyboard(event))
+		}
+
+		for _, event := range test.mouse {
+			got = append(got, test.filter.MatchMouse(event))
+		}
+
+		if len(got) != len(test.exp) {
+			t.Fatalf("%s: got %v, expected %v", test.name, got, test.exp)
+		}
+
+		for i := range test.exp {
+			if got[i] != test.exp[i] {
+				t.Fatalf("%s: got %v, expected %v", test.name, got, test.exp)
+			}
+		}
+	}
+}
+
+func TestFilterMatchEvent(t *testing.T) {
+	filter := Or(KeyIs(VirtualKey('A')), ButtonIs(MouseButtonLeft))
+
+	if !filter.Match(KeyEvent{LowLevelKeyboardEvent: filterKeyEvent(VirtualKey('A'), 0)}) {
+		t.Fatal("expected the key event to match")
+	}
+
+	if !filter.Match(MouseEvent{LowLevelMouseEvent: filterMouseEvent(WMLButtonDown, 0)}) {
+		t.Fatal("expected the mouse event to match")
+	}
+
+	if filter.Match(MouseEvent{LowLevelMouseEvent: filterMouseEvent(WMRButtonDown, 0)}) {
+		t.Fatal("expected the mouse event not to match")
+	}
+}
+
+func TestFilterUsesModifiers(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		exp    bool
+	}{
+		{name: "zero value", filter: Filter{}, exp: false},
+		{name: "KeyIs", filter: KeyIs(VKShift), exp: false},
+		{name: "ButtonIs", filter: ButtonIs(MouseButtonLeft), exp: false},
+		{name: "WithModifiers", filter: WithModifiers(ModAlt), exp: true},
+		{name: "And", filter: And(ButtonIs(MouseButtonLeft), WithModifiers(ModAlt)), exp: true},
+		{name: "Or", filter: Or(ButtonIs(MouseButtonLeft), NotInjected()), exp: false},
+		{name: "Not", filter: Not(WithModifiers(ModAlt)), exp: true},
+		{name: "nested", filter: Or(InRect(Rect{}), And(Not(WithModifiers(ModWin)))), exp: true},
+	}
+
+	for _, test := range tests {
+		if test.filter.modifiers != test.exp {
+			t.Fatalf("%s: filter uses modifiers is %t, expected %t",
+				test.name, test.filter.modifiers, test.exp)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package user32util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+)
+
+// Default GestureConfig values.
+const (
+	DefaultGestureMinLength     = 30
+	DefaultGestureSegmentLength = 20
+	DefaultGestureMinScore      = 0.8
+)
+
+// Direction is the direction of a single segment of a gesture stroke.
+type Direction uint8
+
+const (
+	DirectionUp Direction = iota + 1
+	DirectionDown
+	DirectionLeft
+	DirectionRight
+	DirectionUpLeft
+	DirectionUpRight
+	DirectionDownLeft
+	DirectionDownRight
+)
+
+func (o Direction) String() string {
+	switch o {
+	case DirectionUp:
+		return "U"
+	case DirectionDown:
+		return "D"
+	case DirectionLeft:
+		return "L"
+	case DirectionRight:
+		return "R"
+	case DirectionUpLeft:
+		return "UL"
+	case DirectionUpRight:
+		return "UR"
+	case DirectionDownLeft:
+		return "DL"
+	case DirectionDownRight:
+		return "DR"
+	}
+
+	return "?"
+}
+
+// Gesture is a stroke recognized by a GestureRecognizer.
+type Gesture struct {
+	// Button is the mouse button that was held while drawing the stroke.
+	Button MouseButton
+
+	// Points are the cursor positions that make up the stroke.
+	Points []Point
+
+	// Directions is the stroke's direction sequence.
+	Directions []Direction
+
+	// Template is the name of the GestureTemplate that best matches the
+	// stroke. It is empty if no template scored at least the configured
+	// minimum score.
+	Template string
+
+	// Score is the Template's score, from 0 through 1.
+	Score float64
+}
+
+// DirectionsString returns the gesture's direction sequence as a string
+// of space-separated directions (e.g., "D R" for an "L" shape).
+func (o Gesture) DirectionsString() string {
+	strs := make([]string, len(o.Directions))
+	for i, d := range o.Directions {
+		strs[i] = d.String()
+	}
+
+	return strings.Join(strs, " ")
+}
+
+// GestureConfig configures a GestureRecognizer.
+type GestureConfig struct {
+	// Button is the mouse button that is held while drawing gestures.
+	// Zero is replaced with MouseButtonRight.
+	Button MouseButton
+
+	// MinLength is the minimum length of a stroke, in pixels, for it to
+	// be considered a gesture. Zero is replaced with
+	// DefaultGestureMinLength.
+	MinLength float64
+
+	// SegmentLength is the distance, in pixels, that the cursor must
+	// travel before a direction is recorded. Zero is replaced with
+	// DefaultGestureSegmentLength.
+	SegmentLength float64
+
+	// Diagonals enables recognition of the four diagonal directions.
+	Diagonals bool
+
+	// Templates are matched against strokes using the $1 unistroke
+	// recognizer.
+	Templates []GestureTemplate
+
+	// MinScore is the minimum template score (0 through 1) for a template
+	// match to be reported. Zero is replaced with DefaultGestureMinScore.
+	MinScore float64
+
+	// Swallow is the Backend used to replay the gesture button's press
+	// and release when a stroke turns out not to be a gesture (e.g., a
+	// normal right-click). If non-nil, the gesture button's events are
+	// blocked while a stroke is drawn, preventing applications from
+	// seeing them (e.g., opening a context menu). If nil, events are
+	// observed, but never blocked.
+	Swallow Backend
+
+	// OnReplayError is called with the error returned by Swallow when
+	// replaying a swallowed press and release fails, leaving the click
+	// undelivered. It is called from a separate goroutine. If nil, replay
+	// errors are discarded.
+	OnReplayError func(error)
+}
+
+// NewGestureRecognizer creates a new GestureRecognizer that calls fn for
+// each recognized gesture.
+//
+// When the recognizer is fed by a listener, fn is called on the hook's
+// thread. It must return quickly, otherwise Windows may remove the hook.
+// Refer to the LowLevelHooksTimeout documentation for more information.
+func NewGestureRecognizer(config GestureConfig, fn func(Gesture)) *GestureRecognizer {
+	if config.Button == 0 {
+		config.Button = MouseButtonRight
+	}
+	if config.MinLength == 0 {
+		config.MinLength = DefaultGestureMinLength
+	}
+	if config.SegmentLength == 0 {
+		config.SegmentLength = DefaultGestureSegmentLength
+	}
+	if config.MinScore == 0 {
+		config.MinScore = DefaultGestureMinScore
+	}
+
+	templates := make([]dollarTemplate, len(config.Templates))
+	for i, template := range config.Templates {
+		templates[i] = newDollarTemplate(template)
+	}
+
+	return &GestureRecognizer{
+		config:    config,
+		templates: templates,
+		fn:        fn,
+	}
+}
+
+// GestureRecognizer recognizes mouse gestures: strokes drawn while
+// holding a mouse button (by default, the right button). Strokes are
+// recognized as a sequence of directions, and optionally matched against
+// templates using the $1 unistroke recognizer.
+//
+// Events are fed to the recognizer by a mouse listener, typically using
+// the ListenerOption returned by the recognizer's ListenerOption method.
+// Recorded events can be passed to Process directly.
+//
+// GestureRecognizer is not safe for concurrent use.
+type GestureRecognizer struct {
+	config    GestureConfig
+	templates []dollarTemplate
+	fn        func(Gesture)
+	drawing   bool
+	points    []Point
+
+	// replays is the number of replayed gesture button events that
+	// have not been seen by Process yet. It is decremented by the
+	// replay goroutine if sending fails.
+	replays int32
+}
+
+// ListenerOption returns a ListenerOption that feeds a mouse listener's
+// events to the recognizer and blocks the events it swallows.
+func (o *GestureRecognizer) ListenerOption() ListenerOption {
+	return BlockMouseEvents(o.Process)
+}
+
+// Process processes a single mouse event. It returns true if the event
+// should be blocked.
+func (o *GestureRecognizer) Process(event LowLevelMouseEvent) bool {
+	if event.MouseButtonAction() == WMMouseMove {
+		if o.drawing {
+			o.points = append(o.points, event.Struct.Point)
+		}
+
+		return false
+	}
+
+	button, down, ok := event.Button()
+	if !ok || button != o.config.Button {
+		return false
+	}
+
+	// Events replayed by the recognizer must reach applications. They
+	// are counted rather than identified by their signature, which is
+	// absent if the signature is zero or the Swallow Backend does not
+	// sign its input.
+	if event.IsInjected() && atomic.LoadInt32(&o.replays) > 0 {
+		atomic.AddInt32(&o.replays, -1)
+		return false
+	}
+
+	if down {
+		o.drawing = true
+		o.points = append(o.points[:0], event.Struct.Point)
+		return o.config.Swallow != nil
+	}
+
+	if !o.drawing {
+		return false
+	}
+
+	o.drawing = false
+	o.points = append(o.points, event.Struct.Point)
+
+	gesture, ok := o.Recognize(o.points)
+	if ok {
+		o.fn(gesture)
+	} else if o.config.Swallow != nil {
+		// Replay the click asynchronously. Sending input from within
+		// the hook procedure would delay the hook's return.
+		atomic.AddInt32(&o.replays, 2)
+		go o.replay(button)
+	}
+
+	return o.config.Swallow != nil
+}
+
+// replay sends a swallowed press and release of a button using the
+// Swallow Backend, reporting failures to OnReplayError.
+func (o *GestureRecognizer) replay(button MouseButton) {
+	err := o.config.Swallow.SendInputs([]Input{
+		NewMouseInput(button.DownInput()),
+		NewMouseInput(button.UpInput()),
+	})
+	if err == nil {
+		return
+	}
+
+	atomic.AddInt32(&o.replays, -2)
+
+	if o.config.OnReplayError != nil {
+		o.config.OnReplayError(fmt.Errorf("failed to replay swallowed %s click - %w",
+			button, err))
+	}
+}
+
+// Recognize recognizes a stroke made of the specified points. It returns
+// false if the stroke is too short to be a gesture.
+func (o *GestureRecognizer) Recognize(points []Point) (Gesture, bool) {
+	if strokeLength(points) < o.config.MinLength {
+		return Gesture{}, false
+	}
+
+	gesture := Gesture{
+		Button:     o.config.Button,
+		Points:     append([]Point(nil), points...),
+		Directions: RecognizeDirections(points, o.config.SegmentLength, o.config.Diagonals),
+	}
+
+	name, score := dollarRecognize(points, o.templates)
+	if name != "" && score >= o.config.MinScore {
+		gesture.Template = name
+		gesture.Score = score
+	}
+
+	return gesture, true
+}
+
+// RecognizeDirections converts a stroke into a sequence of directions.
+// A direction is recorded each time the cursor travels segmentLength
+// pixels. Consecutive duplicate directions are collapsed, meaning a stroke
+// down and then to the right produces DirectionDown, DirectionRight.
+//
+// If diagonals is false, only the four cardinal directions are reported.
+func RecognizeDirections(points []Point, segmentLength float64, diagonals bool) []Direction {
+	if len(points) == 0 {
+		return nil
+	}
+
+	var directions []Direction
+	anchor := points[0]
+
+	for _, p := range points[1:] {
+		if distance(anchor, p) < segmentLength {
+			continue
+		}
+
+		d := directionOf(float64(p.X-anchor.X), float64(p.Y-anchor.Y), diagonals)
+		if len(directions) == 0 || directions[len(directions)-1] != d {
+			directions = append(directions, d)
+		}
+
+		anchor = p
+	}
+
+	return directions
+}
+
+// directionOf returns the direction of a vector in screen coordinates,
+// where y increases downward.
+func directionOf(dx float64, dy float64, diagonals bool) Direction {
+	angle := math.Atan2(-dy, dx) * 180 / math.Pi
+	if angle < 0 {
+		angle += 360
+	}
+
+	if !diagonals {
+		switch {
+		case angle < 45 || angle >= 315:
+			return DirectionRight
+		case angle < 135:
+			return DirectionUp
+		case angle < 225:
+			return DirectionLeft
+		default:
+			return DirectionDown
+		}
+	}
+
+	sectors := []Direction{
+		DirectionRight, DirectionUpRight, DirectionUp, DirectionUpLeft,
+		DirectionLeft, DirectionDownLeft, DirectionDown, DirectionDownRight,
+	}
+
+	return sectors[int(math.Floor((angle+22.5)/45))%8]
+}
+
+func strokeLength(points []Point) float64 {
+	var length float64
+	for i := 1; i < len(points); i++ {
+		length += distance(points[i-1], points[i])
+	}
+
+	return length
+}
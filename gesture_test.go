@@ -0,0 +1,295 @@
+package user32util
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadGestureTrace loads a recorded point trace from testdata/gestures.
+// Each line of a trace is an "x,y" point. Lines starting with '#' are
+// comments.
+func loadGestureTrace(t *testing.T, name string) []Point {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", "gestures", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var points []Point
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ",")
+		if len(parts) != 2 {
+			t.Fatalf("%s: malformed line %q", name, line)
+		}
+
+		x, err := strconv.ParseInt(parts[0], 10, 32)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		y, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		points = append(points, Point{X: int32(x), Y: int32(y)})
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return points
+}
+
+func loadGestureTemplates(t *testing.T) []GestureTemplate {
+	t.Helper()
+
+	var templates []GestureTemplate
+	for _, name := range []string{"caret", "check", "circle", "zigzag"} {
+		templates = append(templates, GestureTemplate{
+			Name:   name,
+			Points: loadGestureTrace(t, "template-"+name+".txt"),
+		})
+	}
+
+	return templates
+}
+
+// gestureEvents converts a trace into the events of a stroke drawn while
+// holding the right mouse button.
+func gestureEvents(points []Point) []LowLevelMouseEvent {
+	events := make([]LowLevelMouseEvent, 0, len(points)+1)
+
+	for i, p := range points {
+		action := WMMouseMove
+		switch i {
+		case 0:
+			action = WMRButtonDown
+		case len(points) - 1:
+			action = WMRButtonUp
+		}
+
+		events = append(events, LowLevelMouseEvent{
+			WParam: uintptr(action),
+			Struct: &MsllHookStruct{Point: p},
+		})
+	}
+
+	return events
+}
+
+func TestGestureRecognizerMatchesRecordedTraces(t *testing.T) {
+	recognizer := NewGestureRecognizer(GestureConfig{
+		Templates: loadGestureTemplates(t),
+	}, nil)
+
+	tests := []struct {
+		trace       string
+		expTemplate string
+	}{
+		{trace: "match-caret-1.txt", expTemplate: "caret"},
+		{trace: "match-caret-2.txt", expTemplate: "caret"},
+		{trace: "match-check-1.txt", expTemplate: "check"},
+		{trace: "match-check-2.txt", expTemplate: "check"},
+		{trace: "match-circle-1.txt", expTemplate: "circle"},
+		{trace: "match-circle-2.txt", expTemplate: "circle"},
+		{trace: "match-zigzag-1.txt", expTemplate: "zigzag"},
+		{trace: "match-zigzag-2.txt", expTemplate: "zigzag"},
+	}
+
+	for _, test := range tests {
+		gesture, ok := recognizer.Recognize(loadGestureTrace(t, test.trace))
+		if !ok {
+			t.Fatalf("%s: stroke was not recognized as a gesture", test.trace)
+		}
+
+		if gesture.Template != test.expTemplate {
+			t.Fatalf("%s: matched template %q with score %f, expected %q",
+				test.trace, gesture.Template, gesture.Score, test.expTemplate)
+		}
+	}
+}
+
+func TestGestureRecognizerRejectsRecordedTraces(t *testing.T) {
+	recognizer := NewGestureRecognizer(GestureConfig{
+		Templates: loadGestureTemplates(t),
+	}, nil)
+
+	for _, trace := range []string{"reject-line.txt", "reject-scribble.txt"} {
+		gesture, ok := recognizer.Recognize(loadGestureTrace(t, trace))
+		if !ok {
+			t.Fatalf("%s: stroke was not recognized as a gesture", trace)
+		}
+
+		if gesture.Template != "" {
+			t.Fatalf("%s: unexpectedly matched template %q with score %f",
+				trace, gesture.Template, gesture.Score)
+		}
+	}
+
+	_, ok := recognizer.Recognize(loadGestureTrace(t, "reject-short.txt"))
+	if ok {
+		t.Fatal("reject-short.txt: short stroke was recognized as a gesture")
+	}
+}
+
+func TestGestureRecognizerProcessRecordedTrace(t *testing.T) {
+	var gestures []Gesture
+	backend := &fakeBackend{}
+
+	recognizer := NewGestureRecognizer(GestureConfig{
+		Templates: loadGestureTemplates(t),
+		Swallow:   backend,
+	}, func(gesture Gesture) {
+		gestures = append(gestures, gesture)
+	})
+
+	for _, event := range gestureEvents(loadGestureTrace(t, "match-zigzag-1.txt")) {
+		blocked := recognizer.Process(event)
+		if blocked != (event.MouseButtonAction() != WMMouseMove) {
+			t.Fatalf("%s event blocked: %t", MessageName(event.WParam), blocked)
+		}
+	}
+
+	if len(gestures) != 1 || gestures[0].Template != "zigzag" {
+		t.Fatalf("expected a single zigzag gesture, got %+v", gestures)
+	}
+
+	if directions := gestures[0].DirectionsString(); directions != "R L R" {
+		t.Fatalf("got directions %q, expected \"R L R\"", directions)
+	}
+
+	if len(backend.inputs()) > 0 {
+		t.Fatal("expected a recognized gesture not to be replayed")
+	}
+}
+
+func TestGestureRecognizerReplaysRejectedClick(t *testing.T) {
+	sent := make(chan []Input, 1)
+	backend := &fakeBackend{
+		onSend: func(inputs []Input) {
+			sent <- inputs
+		},
+	}
+
+	recognizer := NewGestureRecognizer(GestureConfig{Swallow: backend}, func(gesture Gesture) {
+		t.Errorf("unexpected gesture %+v", gesture)
+	})
+
+	for _, event := range gestureEvents(loadGestureTrace(t, "reject-short.txt")) {
+		recognizer.Process(event)
+	}
+
+	select {
+	case inputs := <-sent:
+		exp := inputValues([]Input{
+			NewMouseInput(MouseButtonRight.DownInput()),
+			NewMouseInput(MouseButtonRight.UpInput()),
+		})
+
+		got := inputValues(inputs)
+		if len(got) != len(exp) || got[0] != exp[0] || got[1] != exp[1] {
+			t.Fatalf("replayed %+v, expected %+v", got, exp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the click to be replayed")
+	}
+}
+
+func TestGestureRecognizerReportsReplayError(t *testing.T) {
+	sendErr := errors.New("send failed")
+	errs := make(chan error, 1)
+
+	recognizer := NewGestureRecognizer(GestureConfig{
+		Swallow: &fakeBackend{sendErr: sendErr},
+		OnReplayError: func(err error) {
+			errs <- err
+		},
+	}, nil)
+
+	for _, event := range gestureEvents(loadGestureTrace(t, "reject-short.txt")) {
+		recognizer.Process(event)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, sendErr) {
+			t.Fatalf("got error %v, expected it to wrap %v", err, sendErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the replay error")
+	}
+}
+
+func TestGestureRecognizerPassesUnsignedReplays(t *testing.T) {
+	sent := make(chan []Input, 1)
+	backend := &fakeBackend{
+		onSend: func(inputs []Input) {
+			sent <- inputs
+		},
+	}
+
+	recognizer := NewGestureRecognizer(GestureConfig{Swallow: backend}, func(gesture Gesture) {
+		t.Errorf("unexpected gesture %+v", gesture)
+	})
+
+	press := LowLevelMouseEvent{
+		WParam: uintptr(WMRButtonDown),
+		Struct: &MsllHookStruct{Point: Point{X: 10, Y: 10}},
+	}
+	release := LowLevelMouseEvent{
+		WParam: uintptr(WMRButtonUp),
+		Struct: &MsllHookStruct{Point: Point{X: 10, Y: 10}},
+	}
+
+	if !recognizer.Process(press) || !recognizer.Process(release) {
+		t.Fatal("expected the click to be swallowed")
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the click to be replayed")
+	}
+
+	// The replayed events are injected, but not signed.
+	replayedPress := press
+	replayedPress.Struct = &MsllHookStruct{Flags: LLMHFInjected}
+	replayedRelease := release
+	replayedRelease.Struct = &MsllHookStruct{Flags: LLMHFInjected}
+
+	if replayedPress.IsOwnInjection() {
+		t.Fatal("expected the replayed events not to be signed")
+	}
+
+	if recognizer.Process(replayedPress) || recognizer.Process(replayedRelease) {
+		t.Fatal("expected the replayed click to pass through")
+	}
+
+	if !recognizer.Process(press) || !recognizer.Process(release) {
+		t.Fatal("expected the next click to be swallowed")
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the second click to be replayed")
+	}
+}
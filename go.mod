@@ -1,5 +1,5 @@
 module github.com/stephen-fox/user32util
 
-go 1.12
+go 1.21
 
 require golang.org/x/sys v0.0.0-20190530182044-ad28b68e88f1
@@ -0,0 +1,492 @@
+package user32util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultSubscriptionBufferSize is the default size of a Subscription's
+// event buffer.
+const DefaultSubscriptionBufferSize = 64
+
+// EventKinds is a set of event kinds.
+type EventKinds uint8
+
+const (
+	KeyboardEvents EventKinds = 1 << iota
+	MouseEvents
+
+	AllEvents = KeyboardEvents | MouseEvents
+)
+
+var sharedHub struct {
+	mu  sync.Mutex
+	hub *Hub
+}
+
+// SharedHub returns the process-wide Hub, creating it if it does not
+// exist yet or was closed. The Hub uses the process-wide User32DLL,
+// acquiring its own reference (refer to Acquire) that is released when
+// the Hub is closed.
+func SharedHub() (*Hub, error) {
+	sharedHub.mu.Lock()
+	defer sharedHub.mu.Unlock()
+
+	if sharedHub.hub != nil && !sharedHub.hub.isClosed() {
+		return sharedHub.hub, nil
+	}
+
+	user32, err := Acquire()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire user32 dll - %s", err)
+	}
+
+	hub := NewHub(user32)
+	hub.ownsUser32 = true
+	sharedHub.hub = hub
+
+	return hub, nil
+}
+
+// NewHub creates a new Hub. Most programs should use SharedHub instead.
+func NewHub(user32 *User32DLL) *Hub {
+	hub := &Hub{
+		user32:    user32,
+		modifiers: newModifierTracker(),
+		stats:     newHookStats(),
+	}
+
+	hub.subs.Store([]*Subscription{})
+
+	return hub
+}
+
+// Hub shares a single keyboard hook and a single mouse hook between any
+// number of subscribers. This avoids the cost of installing a separate
+// hook for each component of a program that listens for input.
+//
+// Hooks are installed when the first subscriber for an event kind
+// subscribes, and removed when the last one unsubscribes. Both hooks are
+// installed on a single thread (refer to InputListener for more
+// information). When the set of hooks changes, the new hooks are
+// installed before the old ones are removed, meaning events are neither
+// missed nor duplicated. If the new hooks cannot be installed, the old
+// ones are kept.
+//
+// Each subscriber receives events on its own buffered channel, meaning
+// a slow subscriber cannot delay the hook or other subscribers. Events
+// are dropped, and counted, when a subscriber's buffer is full.
+//
+// Subscribers are offered each event in order of descending priority
+// (subscribers with the same priority are ordered by subscription time).
+// A subscriber may block an event, in which case it is not passed to
+// lower priority subscribers or other applications.
+//
+// Hub is safe for concurrent use.
+type Hub struct {
+	// seq and generation are accessed atomically. They are the first
+	// fields to guarantee 64-bit alignment on 32-bit platforms.
+	seq uint64
+
+	// generation identifies the hook thread that dispatches events.
+	// Hooks belonging to other generations pass events through, which
+	// prevents duplicate events while old and new hooks overlap.
+	generation uint64
+
+	user32     *User32DLL
+	ownsUser32 bool
+	mu         sync.Mutex
+	nextID     uint64
+	sorted     []*Subscription
+	hooks      *hookThread
+	kinds      EventKinds
+	closed     bool
+
+	// modifiers and stats outlive the hook threads, which are replaced when the
+	// set of event kinds changes.
+	modifiers *modifierTracker
+	stats     *hookStats
+
+	// slots holds the keyboard and mouse hooks' callbacks, indexed by
+	// the parity of the generation that installed them. The old and new
+	// hooks overlap while they are replaced, so consecutive generations
+	// use different slots.
+	slots [2]hubHookSlots
+
+	// subs holds an immutable []*Subscription that is read by
+	// the hook thread without locking.
+	subs atomic.Value
+}
+
+// hubHookSlots holds the callbacks of a single hook generation.
+type hubHookSlots struct {
+	keyboard hookProcSlot
+	mouse    hookProcSlot
+}
+
+// SubscribeOptions configures a Subscription.
+type SubscribeOptions struct {
+	// Kinds is the kinds of events that the subscriber receives.
+	// Zero is replaced with AllEvents.
+	Kinds EventKinds
+
+	// Filter determines which events are delivered to the subscriber.
+	// The zero value matches every event. Filter is evaluated on the
+	// hook's thread.
+	Filter Filter
+
+	// Priority determines the order in which subscribers are offered
+	// events. Higher priorities are offered events first.
+	Priority int
+
+	// BufferSize is the size of the subscriber's event buffer. Zero is
+	// replaced with DefaultSubscriptionBufferSize.
+	BufferSize int
+
+	// Block, if non-nil, is called for each event delivered to the
+	// subscriber. If it returns true, the event is blocked. Block is
+	// called on the hook's thread and must return quickly. The event's
+	// Struct field is only valid for the duration of the call.
+	Block func(event Event) bool
+}
+
+// Stats returns a snapshot of the Hub's counters. QueueDepth and Dropped
+// are the totals of the current subscriptions.
+func (o *Hub) Stats() ListenerStats {
+	stats := o.stats.snapshot()
+	stats.Queued = true
+
+	for _, sub := range o.subs.Load().([]*Subscription) {
+		stats.QueueDepth += len(sub.events)
+		stats.Dropped += sub.Dropped()
+	}
+
+	return stats
+}
+
+// Subscribe creates a new Subscription, installing hooks as needed.
+func (o *Hub) Subscribe(options SubscribeOptions) (*Subscription, error) {
+	if options.Kinds == 0 {
+		options.Kinds = AllEvents
+	}
+
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultSubscriptionBufferSize
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil, errHubClosed
+	}
+
+	o.nextID++
+	sub := &Subscription{
+		hub:     o,
+		id:      o.nextID,
+		options: options,
+		events:  make(chan Event, options.BufferSize),
+	}
+
+	sorted := append(append([]*Subscription(nil), o.sorted...), sub)
+	sort.SliceStable(sorted, func(i int, j int) bool {
+		return sorted[i].options.Priority > sorted[j].options.Priority
+	})
+
+	err := o.setSubscriptions(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// Unsubscribe removes a Subscription and closes its event channel,
+// removing hooks that are no longer needed.
+func (o *Hub) Unsubscribe(sub *Subscription) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var remaining []*Subscription
+	for _, existing := range o.sorted {
+		if existing != sub {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	if len(remaining) == len(o.sorted) {
+		return nil
+	}
+
+	err := o.setSubscriptions(remaining)
+
+	sub.close()
+
+	return err
+}
+
+// Close removes all subscriptions and hooks. The Hub cannot be used
+// after it is closed. If the Hub was created by SharedHub, its reference
+// to the process-wide User32DLL is released.
+func (o *Hub) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	o.closed = true
+
+	subs := o.sorted
+	err := o.setSubscriptions(nil)
+
+	for _, sub := range subs {
+		sub.close()
+	}
+
+	if o.ownsUser32 {
+		releaseErr := o.user32.Release()
+		if err == nil {
+			err = releaseErr
+		}
+	}
+
+	return err
+}
+
+func (o *Hub) isClosed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.closed
+}
+
+// setSubscriptions replaces the Hub's subscriptions, reinstalling hooks
+// if the set of event kinds changes. The caller must hold o.mu.
+//
+// The new hooks are installed before the old ones are released. If they
+// cannot be installed, the old hooks remain in place. This is not an
+// error if the old hooks already cover the new set of event kinds.
+func (o *Hub) setSubscriptions(sorted []*Subscription) error {
+	var kinds EventKinds
+	for _, sub := range sorted {
+		kinds |= sub.options.Kinds
+	}
+
+	if kinds != o.kinds {
+		err := o.reinstall(kinds)
+		if err != nil && kinds&^o.kinds != 0 {
+			return err
+		}
+	}
+
+	o.sorted = sorted
+	o.subs.Store(append([]*Subscription(nil), sorted...))
+
+	return nil
+}
+
+// reinstall replaces the installed hooks with hooks for the specified
+// event kinds. The installed hooks are left untouched if an error
+// occurs. The caller must hold o.mu.
+func (o *Hub) reinstall(kinds EventKinds) error {
+	generation := atomic.LoadUint64(&o.generation) + 1
+
+	var hooks *hookThread
+	if kinds != 0 {
+		if kinds&KeyboardEvents != 0 && o.kinds&KeyboardEvents == 0 {
+			// Keys may have been released while the keyboard
+			// hook was not installed.
+			o.modifiers.reset()
+		}
+
+		var err error
+		hooks, err = setWindowsHookExW(o.hookSpecs(kinds, generation), listenerConfig{stats: o.stats}, o.user32)
+		if err != nil {
+			return err
+		}
+	}
+
+	atomic.StoreUint64(&o.generation, generation)
+
+	if o.hooks != nil {
+		o.hooks.release()
+	}
+
+	o.hooks = hooks
+	o.kinds = kinds
+
+	return nil
+}
+
+// hookSpecs returns the hooks for the specified event kinds. The hooks
+// only dispatch events while generation is the Hub's current generation.
+func (o *Hub) hookSpecs(kinds EventKinds, generation uint64) []hookSpec {
+	var specs []hookSpec
+
+	slots := &o.slots[generation%2]
+
+	if kinds&KeyboardEvents != 0 {
+		specs = append(specs, hookSpec{
+			hookID: whKeyboardLl,
+			slot:   &slots.keyboard,
+			callBack: newKeyboardHook(listenerConfig{
+				modifiers: o.modifiers,
+				keyboardBlocker: func(event LowLevelKeyboardEvent) bool {
+					if atomic.LoadUint64(&o.generation) != generation {
+						return false
+					}
+
+					return o.dispatch(KeyEvent{LowLevelKeyboardEvent: event, Seq: atomic.AddUint64(&o.seq, 1)})
+				},
+			}, func(LowLevelKeyboardEvent) {}),
+		})
+	}
+
+	if kinds&MouseEvents != 0 {
+		config := listenerConfig{
+			modifiers: o.modifiers,
+		}
+
+		// Without a keyboard hook, the tracker is not updated.
+		if kinds&KeyboardEvents == 0 {
+			config.modifiers = nil
+			config.asyncModifiers = o.user32
+		}
+
+		config.mouseBlocker = func(event LowLevelMouseEvent) bool {
+			if atomic.LoadUint64(&o.generation) != generation {
+				return false
+			}
+
+			return o.dispatch(MouseEvent{LowLevelMouseEvent: event, Seq: atomic.AddUint64(&o.seq, 1)})
+		}
+
+		specs = append(specs, hookSpec{
+			hookID:   whMouseLl,
+			callBack: newMouseHook(config, func(LowLevelMouseEvent) {}),
+			slot:     &slots.mouse,
+		})
+	}
+
+	return specs
+}
+
+// dispatch offers an event to each subscriber in priority order,
+// returning true if a subscriber blocked it.
+func (o *Hub) dispatch(event Event) bool {
+	var detached Event
+
+	for _, sub := range o.subs.Load().([]*Subscription) {
+		if !sub.wants(event) {
+			continue
+		}
+
+		if detached == nil {
+			detached = detachEvent(event)
+		}
+
+		sub.deliver(detached)
+
+		if sub.options.Block != nil && sub.options.Block(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detachEvent returns a copy of event whose Struct field remains valid
+// after the hook callback returns.
+func detachEvent(event Event) Event {
+	switch e := event.(type) {
+	case KeyEvent:
+		s := *e.Struct
+		e.Struct = &s
+		return e
+	case MouseEvent:
+		s := *e.Struct
+		e.Struct = &s
+		return e
+	}
+
+	return event
+}
+
+var errHubClosed = errors.New("hub is closed")
+
+// Subscription is a single subscriber's registration with a Hub.
+type Subscription struct {
+	// dropped is accessed atomically. It is the first field to
+	// guarantee 64-bit alignment on 32-bit platforms.
+	dropped uint64
+
+	hub     *Hub
+	id      uint64
+	options SubscribeOptions
+	mu      sync.Mutex
+	events  chan Event
+	closed  bool
+}
+
+// Events returns the channel that the subscriber's events are delivered
+// on. The channel is closed when the subscription is removed.
+func (o *Subscription) Events() <-chan Event {
+	return o.events
+}
+
+// Dropped returns the number of events that were dropped because the
+// subscriber's buffer was full.
+func (o *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&o.dropped)
+}
+
+// Unsubscribe removes the subscription from its Hub.
+func (o *Subscription) Unsubscribe() error {
+	return o.hub.Unsubscribe(o)
+}
+
+func (o *Subscription) wants(event Event) bool {
+	switch event.(type) {
+	case KeyEvent:
+		if o.options.Kinds&KeyboardEvents == 0 {
+			return false
+		}
+	case MouseEvent:
+		if o.options.Kinds&MouseEvents == 0 {
+			return false
+		}
+	}
+
+	return o.options.Filter.Match(event)
+}
+
+func (o *Subscription) deliver(event Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+
+	select {
+	case o.events <- event:
+	default:
+		atomic.AddUint64(&o.dropped, 1)
+	}
+}
+
+func (o *Subscription) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.closed {
+		o.closed = true
+		close(o.events)
+	}
+}
@@ -0,0 +1,238 @@
+package user32util
+
+import (
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// newTestHub returns a Hub whose hooks are considered to be installed,
+// meaning subscribing does not install hooks. Events are dispatched by
+// calling the callbacks returned by hookSpecs directly.
+func newTestHub() *Hub {
+	hub := NewHub(nil)
+	hub.kinds = AllEvents
+
+	return hub
+}
+
+// hubKeyDown calls the keyboard hook in specs with a key press,
+// returning true if the event was blocked.
+func hubKeyDown(specs []hookSpec, vk VirtualKey) bool {
+	s := KbdllHookStruct{VkCode: uint32(vk)}
+
+	for _, spec := range specs {
+		if spec.hookID == whKeyboardLl {
+			return spec.callBack(0, uintptr(WMKeyDown), uintptr(unsafe.Pointer(&s)))
+		}
+	}
+
+	panic("no keyboard hook")
+}
+
+// hubMouseMove calls the mouse hook in specs with a mouse move,
+// returning true if the event was blocked.
+func hubMouseMove(specs []hookSpec, point Point) bool {
+	s := MsllHookStruct{Point: point}
+
+	for _, spec := range specs {
+		if spec.hookID == whMouseLl {
+			return spec.callBack(0, uintptr(WMMouseMove), uintptr(unsafe.Pointer(&s)))
+		}
+	}
+
+	panic("no mouse hook")
+}
+
+func TestHubDispatchPriorityAndBlocking(t *testing.T) {
+	hub := newTestHub()
+
+	var offered []string
+
+	subscribe := func(name string, priority int, blocked VirtualKey) *Subscription {
+		sub, err := hub.Subscribe(SubscribeOptions{
+			Kinds:    AllEvents,
+			Priority: priority,
+			Block: func(event Event) bool {
+				offered = append(offered, name)
+
+				key, ok := event.(KeyEvent)
+				return ok && blocked != 0 && key.Struct.VkCode == uint32(blocked)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return sub
+	}
+
+	low := subscribe("low", 0, 0)
+	high := subscribe("high", 10, 0)
+	mid := subscribe("mid", 5, VirtualKey('B'))
+	lowSecond := subscribe("low-second", 0, 0)
+
+	atomic.StoreUint64(&hub.generation, 1)
+	specs := hub.hookSpecs(AllEvents, 1)
+
+	if hubKeyDown(specs, VirtualKey('A')) {
+		t.Fatal("expected A not to be blocked")
+	}
+
+	exp := []string{"high", "mid", "low", "low-second"}
+	if len(offered) != len(exp) {
+		t.Fatalf("A was offered to %v, expected %v", offered, exp)
+	}
+	for i := range exp {
+		if offered[i] != exp[i] {
+			t.Fatalf("A was offered to %v, expected %v", offered, exp)
+		}
+	}
+
+	offered = nil
+
+	if !hubKeyDown(specs, VirtualKey('B')) {
+		t.Fatal("expected B to be blocked")
+	}
+
+	exp = []string{"high", "mid"}
+	if len(offered) != len(exp) || offered[0] != exp[0] || offered[1] != exp[1] {
+		t.Fatalf("B was offered to %v, expected %v", offered, exp)
+	}
+
+	expCounts := map[*Subscription]int{high: 2, mid: 2, low: 1, lowSecond: 1}
+	for sub, count := range expCounts {
+		if got := len(sub.Events()); got != count {
+			t.Fatalf("subscriber with priority %d has %d events, expected %d",
+				sub.options.Priority, got, count)
+		}
+	}
+
+	first := (<-high.Events()).(KeyEvent)
+	second := (<-high.Events()).(KeyEvent)
+	if second.Seq != first.Seq+1 {
+		t.Fatalf("got sequence numbers %d and %d, expected consecutive numbers",
+			first.Seq, second.Seq)
+	}
+	if first.Struct.VkCode != 'A' || second.Struct.VkCode != 'B' {
+		t.Fatalf("got keys %d and %d, expected A and B", first.Struct.VkCode, second.Struct.VkCode)
+	}
+}
+
+func TestHubDispatchFiltersByKind(t *testing.T) {
+	hub := newTestHub()
+
+	// Keeps the set of event kinds unchanged.
+	_, err := hub.Subscribe(SubscribeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyboard, err := hub.Subscribe(SubscribeOptions{Kinds: KeyboardEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mouse, err := hub.Subscribe(SubscribeOptions{Kinds: MouseEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreUint64(&hub.generation, 1)
+	specs := hub.hookSpecs(AllEvents, 1)
+
+	hubKeyDown(specs, VirtualKey('A'))
+	hubMouseMove(specs, Point{X: 1, Y: 2})
+
+	if len(keyboard.Events()) != 1 {
+		t.Fatalf("keyboard subscriber has %d events, expected 1", len(keyboard.Events()))
+	}
+	if _, ok := (<-keyboard.Events()).(KeyEvent); !ok {
+		t.Fatal("keyboard subscriber received a non-keyboard event")
+	}
+
+	if len(mouse.Events()) != 1 {
+		t.Fatalf("mouse subscriber has %d events, expected 1", len(mouse.Events()))
+	}
+	if event, ok := (<-mouse.Events()).(MouseEvent); !ok || event.Struct.Point != (Point{X: 1, Y: 2}) {
+		t.Fatalf("mouse subscriber received %+v", event)
+	}
+}
+
+func TestHubDispatchGenerations(t *testing.T) {
+	hub := newTestHub()
+
+	sub, err := hub.Subscribe(SubscribeOptions{
+		Block: func(Event) bool {
+			return true
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreUint64(&hub.generation, 1)
+	old := hub.hookSpecs(AllEvents, 1)
+	current := hub.hookSpecs(AllEvents, 2)
+
+	if !hubKeyDown(old, VirtualKey('A')) {
+		t.Fatal("expected the current generation's hook to dispatch events")
+	}
+
+	if hubKeyDown(current, VirtualKey('A')) {
+		t.Fatal("expected a newer generation's hook to pass events through")
+	}
+
+	atomic.StoreUint64(&hub.generation, 2)
+
+	if hubKeyDown(old, VirtualKey('A')) || hubMouseMove(old, Point{}) {
+		t.Fatal("expected an old generation's hooks to pass events through")
+	}
+
+	if !hubKeyDown(current, VirtualKey('A')) || !hubMouseMove(current, Point{}) {
+		t.Fatal("expected the current generation's hooks to dispatch events")
+	}
+
+	if got := len(sub.Events()); got != 3 {
+		t.Fatalf("got %d events, expected 3", got)
+	}
+}
+
+func TestHubReusesHookSlots(t *testing.T) {
+	hub := newTestHub()
+
+	slotsOf := func(generation uint64) (*hookProcSlot, *hookProcSlot) {
+		specs := hub.hookSpecs(AllEvents, generation)
+		return specs[0].slot, specs[1].slot
+	}
+
+	keyboard1, mouse1 := slotsOf(1)
+	keyboard2, mouse2 := slotsOf(2)
+	keyboard3, mouse3 := slotsOf(3)
+
+	if keyboard1 == nil || mouse1 == nil || keyboard1 == mouse1 {
+		t.Fatal("expected the keyboard and mouse hooks to use separate slots")
+	}
+
+	if keyboard1 == keyboard2 || mouse1 == mouse2 {
+		t.Fatal("expected consecutive generations to use different slots")
+	}
+
+	if keyboard1 != keyboard3 || mouse1 != mouse3 {
+		t.Fatal("expected alternate generations to reuse the same slots")
+	}
+
+	var calls []int
+	for i := 0; i < 3; i++ {
+		i := i
+		keyboard1.use(func(int, uintptr, uintptr) uintptr {
+			calls = append(calls, i)
+			return 0
+		})
+	}
+
+	keyboard1.proc.Load().(func(int, uintptr, uintptr) uintptr)(0, 0, 0)
+	if len(calls) != 1 || calls[0] != 2 {
+		t.Fatalf("got calls %v, expected the latest procedure to be called", calls)
+	}
+}
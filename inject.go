@@ -0,0 +1,46 @@
+package user32util
+
+import (
+	"sync/atomic"
+)
+
+// DefaultInjectionSignature is the value written to the DwExtraInfo field
+// of inputs sent by this library when no other signature has been set
+// using SetInjectionSignature.
+const DefaultInjectionSignature uintptr = 0x75333275
+
+var injectionSignature = DefaultInjectionSignature
+
+// SetInjectionSignature sets the value that this library writes to the
+// DwExtraInfo field of the MouseInput and KeybdInput structures that it
+// sends. Listeners compare an event's DwExtraInfo against this value to
+// determine if the library injected the event.
+//
+// A signature of zero disables tagging.
+func SetInjectionSignature(signature uintptr) {
+	atomic.StoreUintptr(&injectionSignature, signature)
+}
+
+// InjectionSignature returns the value that this library writes to
+// the DwExtraInfo field of the inputs that it sends.
+func InjectionSignature() uintptr {
+	return atomic.LoadUintptr(&injectionSignature)
+}
+
+// signExtraInfo returns the current injection signature if extraInfo is
+// zero. Otherwise, the caller's value is returned unmodified.
+func signExtraInfo(extraInfo uintptr) uintptr {
+	if extraInfo != 0 {
+		return extraInfo
+	}
+
+	return InjectionSignature()
+}
+
+// isOwnExtraInfo returns true if extraInfo matches the current
+// injection signature.
+func isOwnExtraInfo(extraInfo uintptr) bool {
+	signature := InjectionSignature()
+
+	return signature != 0 && extraInfo == signature
+}
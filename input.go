@@ -0,0 +1,131 @@
+package user32util
+
+// Event is a single event reported by an InputListener. It is either
+// a KeyEvent or a MouseEvent.
+type Event interface {
+	// Sequence returns the event's position in the listener's
+	// event stream, starting at 1.
+	Sequence() uint64
+}
+
+// KeyEvent is a keyboard Event.
+type KeyEvent struct {
+	LowLevelKeyboardEvent
+
+	// Seq is the event's sequence number.
+	Seq uint64
+}
+
+// Sequence returns the event's sequence number.
+func (o KeyEvent) Sequence() uint64 {
+	return o.Seq
+}
+
+// MouseEvent is a mouse Event.
+type MouseEvent struct {
+	LowLevelMouseEvent
+
+	// Seq is the event's sequence number.
+	Seq uint64
+}
+
+// Sequence returns the event's sequence number.
+func (o MouseEvent) Sequence() uint64 {
+	return o.Seq
+}
+
+type OnInputEventFunc func(event Event)
+
+// NewInputListener instantiates a new input listener that reports both
+// keyboard and mouse input using the LowLevelKeyboardProc and
+// LowLevelMouseProc Windows hooks.
+//
+// Refer to InputListener for more information.
+func NewInputListener(fn OnInputEventFunc, user32 *User32DLL, options ...ListenerOption) (*InputListener, error) {
+	config := newListenerConfig(options)
+
+	// Both hooks are called on the same thread, so the counter does
+	// not need to be synchronized. When mouse moves are coalesced,
+	// deliveries are serialized by the coalescer instead.
+	var seq uint64
+
+	hooks, err := setWindowsHookExW([]hookSpec{
+		{
+			hookID: whKeyboardLl,
+			callBack: newKeyboardHook(config, func(event LowLevelKeyboardEvent) {
+				seq++
+				fn(KeyEvent{LowLevelKeyboardEvent: event, Seq: seq})
+			}),
+		},
+		{
+			hookID: whMouseLl,
+			callBack: newMouseHook(config, func(event LowLevelMouseEvent) {
+				seq++
+				fn(MouseEvent{LowLevelMouseEvent: event, Seq: seq})
+			}),
+		},
+	}, config, user32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InputListener{
+		user32: user32,
+		fn:     fn,
+		hooks:  hooks,
+		moves:  config.moves,
+	}, nil
+}
+
+// InputListener represents an instance of both the LowLevelKeyboardProc
+// and LowLevelMouseProc Windows hooks, installed on a single thread.
+//
+// Because both hooks share a thread, keyboard and mouse events are
+// reported in a single stream in the order that Windows delivers them.
+// This makes it possible to reason about the relative order of key and
+// mouse input (e.g., a Ctrl+Click). Each event is assigned a sequence
+// number that reflects its position in the stream.
+//
+// Events are delivered synchronously on the hook's thread. The Struct
+// field of an event is only valid for the duration of the callback.
+type InputListener struct {
+	user32 *User32DLL
+	fn     OnInputEventFunc
+	hooks  *hookThread
+	moves  *moveCoalescer
+}
+
+// OnDone returns a channel that is written to when the event listener exits.
+// A non-nil error is written if an error caused the listener to exit.
+func (o *InputListener) OnDone() <-chan error {
+	return o.hooks.done
+}
+
+// WatchdogEvents returns a channel that reports incidents detected by
+// the listener's watchdog. The channel is closed when the listener is
+// released. It returns nil if the listener was not created with the
+// WithWatchdog option.
+func (o *InputListener) WatchdogEvents() <-chan WatchdogEvent {
+	return o.hooks.watchdogEvents()
+}
+
+// Stats returns a snapshot of the listener's counters.
+func (o *InputListener) Stats() ListenerStats {
+	return o.hooks.stats.snapshot()
+}
+
+// MouseMoveStats returns counters describing how the listener coalesced
+// or sampled WMMouseMove events. The counters are zero unless the
+// CoalesceMouseMoves or SampleMouseMoves options are used.
+func (o *InputListener) MouseMoveStats() MouseMoveStats {
+	return o.moves.stats()
+}
+
+// Release releases the underlying hook handles and stops the listener from
+// receiving any additional events.
+func (o *InputListener) Release() error {
+	o.hooks.release()
+	o.moves.close()
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package user32util
 
 import (
+	"time"
 	"unsafe"
 )
 
@@ -12,6 +13,18 @@ const (
 	WMSystemKeyUp   KeyboardButtonAction = 261
 )
 
+// KbdllHookStruct Flags values.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-kbdllhookstruct
+const (
+	LLKHFExtended        uint32 = 0x01
+	LLKHFLowerILInjected uint32 = 0x02
+	LLKHFInjected        uint32 = 0x10
+	LLKHFAltDown         uint32 = 0x20
+	LLKHFUp              uint32 = 0x80
+)
+
 // KeyboardButtonAction is an alias for the values contained in the
 // wParam field fo LowLevelKeyboardEvent.
 type KeyboardButtonAction uintptr
@@ -20,31 +33,79 @@ type KeyboardButtonAction uintptr
 // the LowLevelKeyboardProc Windows hook.
 //
 // Refer to LowLevelKeyboardEventListener for more information.
-func NewLowLevelKeyboardListener(fn OnLowLevelKeyboardEventFunc, user32 *User32DLL) (*LowLevelKeyboardEventListener, error) {
-	callBack := func(nCode int, wParam uintptr, lParam uintptr) {
-		if nCode == 0 {
-			fn(LowLevelKeyboardEvent{
-				WParam: wParam,
-				LParam: lParam,
-				Struct: (*KbdllHookStruct)(unsafe.Pointer(lParam)),
-			})
-		}
-	}
+func NewLowLevelKeyboardListener(fn OnLowLevelKeyboardEventFunc, user32 *User32DLL, options ...ListenerOption) (*LowLevelKeyboardEventListener, error) {
+	config := newListenerConfig(options)
 
-	handle, threadID, done, err := setWindowsHookExW(whKeyboardLl, callBack, user32)
+	hooks, err := setWindowsHookExW([]hookSpec{
+		{hookID: whKeyboardLl, callBack: newKeyboardHook(config, fn)},
+	}, config, user32)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LowLevelKeyboardEventListener{
-		user32:     user32,
-		hookHandle: handle,
-		threadID:   threadID,
-		fn:         fn,
-		done:       done,
+		user32: user32,
+		fn:     fn,
+		hooks:  hooks,
 	}, nil
 }
 
+// newKeyboardHook returns a hook callback that converts the hook's
+// parameters into a LowLevelKeyboardEvent, applies the listener's options,
+// and then calls fn.
+func newKeyboardHook(config listenerConfig, fn OnLowLevelKeyboardEventFunc) onHookCalledFunc {
+	clock := NewTickClock()
+
+	return func(nCode int, wParam uintptr, lParam uintptr) bool {
+		if nCode != 0 {
+			return false
+		}
+
+		event := LowLevelKeyboardEvent{
+			WParam: wParam,
+			LParam: lParam,
+			Struct: kbdllHookStruct(lParam),
+		}
+		event.Timestamp = clock.Time(event.Struct.Time)
+		event.Modifiers = config.modifiers.update(event)
+
+		if config.ignoreOwnInjections && event.IsOwnInjection() {
+			return false
+		}
+
+		if !config.filter.MatchKeyboard(event) {
+			return false
+		}
+
+		if config.keyboardBlocker != nil && config.keyboardBlocker(event) {
+			return true
+		}
+
+		if config.moves != nil {
+			config.moves.other(func() {
+				fn(event)
+			})
+			return false
+		}
+
+		fn(event)
+
+		return false
+	}
+}
+
+// kbdllHookStruct converts the lParam of a LowLevelKeyboardProc into
+// a *KbdllHookStruct. The struct is only valid during the hook callback.
+//
+// The lParam points to memory owned by the system, which the checkptr
+// instrumentation enabled by -race cannot validate. Tests pass pointers
+// to Go memory instead, which checkptr would reject.
+//
+//go:nocheckptr
+func kbdllHookStruct(lParam uintptr) *KbdllHookStruct {
+	return (*KbdllHookStruct)(unsafe.Pointer(lParam))
+}
+
 type OnLowLevelKeyboardEventFunc func(event LowLevelKeyboardEvent)
 
 // LowLevelKeyboardEventListener represents an instance of the
@@ -59,27 +120,34 @@ type OnLowLevelKeyboardEventFunc func(event LowLevelKeyboardEvent)
 // Refer to the following Windows API document for more information:
 // https://docs.microsoft.com/en-us/previous-versions/windows/desktop/legacy/ms644985%28v=vs.85%29
 type LowLevelKeyboardEventListener struct {
-	user32     *User32DLL
-	fn         OnLowLevelKeyboardEventFunc
-	hookHandle uintptr
-	threadID   uint32
-	done       <-chan error
+	user32 *User32DLL
+	fn     OnLowLevelKeyboardEventFunc
+	hooks  *hookThread
 }
 
 // OnDone returns a channel that is written to when the event listener exits.
 // A non-nil error is written if an error caused the listener to exit.
 func (o *LowLevelKeyboardEventListener) OnDone() <-chan error {
-	return o.done
+	return o.hooks.done
+}
+
+// WatchdogEvents returns a channel that reports incidents detected by
+// the listener's watchdog. The channel is closed when the listener is
+// released. It returns nil if the listener was not created with the
+// WithWatchdog option.
+func (o *LowLevelKeyboardEventListener) WatchdogEvents() <-chan WatchdogEvent {
+	return o.hooks.watchdogEvents()
+}
+
+// Stats returns a snapshot of the listener's counters.
+func (o *LowLevelKeyboardEventListener) Stats() ListenerStats {
+	return o.hooks.stats.snapshot()
 }
 
 // Release releases the underlying hook handle and stops the listener from
 // receiving any additional events.
 func (o *LowLevelKeyboardEventListener) Release() error {
-	o.user32.postThreadMessageW.Call(uintptr(o.threadID), wmQuit, 0, 0)
-
-	o.user32.unhookWindowsHookEx.Call(o.hookHandle)
-
-	o.hookHandle = 0
+	o.hooks.release()
 
 	return nil
 }
@@ -89,12 +157,32 @@ type LowLevelKeyboardEvent struct {
 	WParam uintptr
 	LParam uintptr
 	Struct *KbdllHookStruct
+
+	// Timestamp is the time at which the event occurred, derived from
+	// Struct.Time. Refer to TickClock for more information.
+	Timestamp time.Time
+
+	// Modifiers are the modifier keys held after the event occurred.
+	Modifiers Modifiers
 }
 
 func (o LowLevelKeyboardEvent) KeyboardButtonAction() KeyboardButtonAction {
 	return KeyboardButtonAction(o.WParam)
 }
 
+// IsInjected returns true if the event was injected by any process
+// (e.g., by calling SendInput).
+func (o LowLevelKeyboardEvent) IsInjected() bool {
+	return o.Struct.Flags&LLKHFInjected != 0
+}
+
+// IsOwnInjection returns true if the event was injected by this library.
+//
+// Refer to SetInjectionSignature for more information.
+func (o LowLevelKeyboardEvent) IsOwnInjection() bool {
+	return o.IsInjected() && isOwnExtraInfo(o.Struct.DwExtraInfo)
+}
+
 // From the Windows API documentation:
 //	Contains information about a low-level keyboard input event.
 //
@@ -0,0 +1,174 @@
+package user32util
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// Key state bits used by GetKeyState and GetKeyboardState.
+const (
+	keyStateDown    = 0x80
+	keyStateToggled = 0x01
+)
+
+// releaseOrder is the order in which ModifierReleaseInputs releases
+// modifier keys.
+var releaseOrder = []VirtualKey{
+	VKLShift, VKRShift,
+	VKLControl, VKRControl,
+	VKLMenu, VKRMenu,
+	VKLWin, VKRWin,
+}
+
+// GetAsyncKeyState returns true if the specified key is down at the time
+// of the call, regardless of which thread has keyboard focus. False is
+// returned if the procedure is unavailable (refer to
+// User32DLL.Capabilities).
+//
+// From the Windows API documentation:
+//	Determines whether a key is up or down at the time the function is
+//	called, and whether the key was pressed after a previous call to
+//	GetAsyncKeyState.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getasynckeystate
+func GetAsyncKeyState(vk VirtualKey, user32 *User32DLL) bool {
+	ret, _, _ := user32.proc(procGetAsyncKeyState).Call(uintptr(vk))
+
+	return uint16(ret)&0x8000 != 0
+}
+
+// GetAsyncModifiers returns the modifier keys that are currently held,
+// as reported by GetAsyncKeyState. Either the left or right key of
+// a modifier satisfies it.
+func GetAsyncModifiers(user32 *User32DLL) Modifiers {
+	var mods Modifiers
+
+	for _, vk := range []VirtualKey{VKShift, VKControl, VKMenu, VKLWin, VKRWin} {
+		if GetAsyncKeyState(vk, user32) {
+			mods |= modifierOf(vk)
+		}
+	}
+
+	return mods
+}
+
+// GetKeyState returns the state of the specified key as of the last
+// message retrieved by the calling thread. It reports whether the key is
+// down, and whether it is toggled (e.g., if CapsLock is on). Both are
+// false if the procedure is unavailable (refer to User32DLL.Capabilities).
+//
+// From the Windows API documentation:
+//	Retrieves the status of the specified virtual key. The status
+//	specifies whether the key is up, down, or toggled (on, off -
+//	alternating each time the key is pressed).
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getkeystate
+func GetKeyState(vk VirtualKey, user32 *User32DLL) (down bool, toggled bool) {
+	ret, _, _ := user32.proc(procGetKeyState).Call(uintptr(vk))
+
+	return uint16(ret)&0x8000 != 0, ret&keyStateToggled != 0
+}
+
+// GetKeyboardState returns the state of all 256 virtual keys as of the
+// last message retrieved by the calling thread.
+//
+// Threads that do not process keyboard messages (which is typical for
+// programs that use this library) may see stale state. Refer to
+// GetKeyboardSnapshot for the state of the keyboard at the time of
+// the call.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getkeyboardstate
+func GetKeyboardState(user32 *User32DLL) (KeyboardSnapshot, error) {
+	var snapshot KeyboardSnapshot
+
+	ret, _, err := user32.proc(procGetKeyboardState).Call(uintptr(unsafe.Pointer(&snapshot[0])))
+	if ret == 0 {
+		return KeyboardSnapshot{}, newCallError("get keyboard state", getKeyboardStateName, err)
+	}
+
+	return snapshot, nil
+}
+
+// GetKeyboardSnapshot returns the state of the keyboard at the time of
+// the call, without installing a hook. The down bit of each key is read
+// using GetAsyncKeyState, and the toggled bit using GetKeyboardState.
+func GetKeyboardSnapshot(user32 *User32DLL) (KeyboardSnapshot, error) {
+	// Calling GetKeyState synchronizes the thread's key state with
+	// the system before GetKeyboardState reads it. Both calls must
+	// be made on the same thread, since the key state is per-thread.
+	runtime.LockOSThread()
+	GetKeyState(0, user32)
+	snapshot, err := GetKeyboardState(user32)
+	runtime.UnlockOSThread()
+	if err != nil {
+		return KeyboardSnapshot{}, err
+	}
+
+	for vk := range snapshot {
+		if GetAsyncKeyState(VirtualKey(vk), user32) {
+			snapshot[vk] |= keyStateDown
+		} else {
+			snapshot[vk] &^= keyStateDown
+		}
+	}
+
+	return snapshot, nil
+}
+
+// KeyboardSnapshot is the state of the 256 virtual keys, indexed by
+// VirtualKey, in the format used by GetKeyboardState. The high-order bit
+// of each byte is set if the key is down, and the low-order bit is set
+// if the key is toggled.
+type KeyboardSnapshot [256]byte
+
+// Down returns true if the key is down.
+func (o KeyboardSnapshot) Down(vk VirtualKey) bool {
+	return vk < 256 && o[vk]&keyStateDown != 0
+}
+
+// Toggled returns true if the key is toggled (e.g., Toggled(VKCapital)
+// returns true if CapsLock is on).
+func (o KeyboardSnapshot) Toggled(vk VirtualKey) bool {
+	return vk < 256 && o[vk]&keyStateToggled != 0
+}
+
+// Pressed returns the keys that are down, in ascending order.
+func (o KeyboardSnapshot) Pressed() []VirtualKey {
+	var keys []VirtualKey
+	for vk := range o {
+		if o[vk]&keyStateDown != 0 {
+			keys = append(keys, VirtualKey(vk))
+		}
+	}
+
+	return keys
+}
+
+// Modifiers returns the modifier keys that are down.
+func (o KeyboardSnapshot) Modifiers() Modifiers {
+	var mods Modifiers
+	for vk := range o {
+		if o[vk]&keyStateDown != 0 {
+			mods |= modifierOf(VirtualKey(vk))
+		}
+	}
+
+	return mods
+}
+
+// ModifierReleaseInputs returns inputs that release the modifier keys
+// that are down. Sending them before typing text prevents a modifier
+// held by the user (e.g., Shift) from changing the typed characters.
+func (o KeyboardSnapshot) ModifierReleaseInputs() []Input {
+	var inputs []Input
+	for _, vk := range releaseOrder {
+		if o.Down(vk) {
+			inputs = append(inputs, NewKeybdInput(KeyUpInput(vk)))
+		}
+	}
+
+	return inputs
+}
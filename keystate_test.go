@@ -0,0 +1,112 @@
+package user32util
+
+import (
+	"reflect"
+	"testing"
+)
+
+// snapshotWith returns a KeyboardSnapshot in which the specified keys
+// have the specified state bits set.
+func snapshotWith(bits byte, keys ...VirtualKey) KeyboardSnapshot {
+	var snapshot KeyboardSnapshot
+	for _, vk := range keys {
+		snapshot[vk] |= bits
+	}
+
+	return snapshot
+}
+
+func TestKeyboardSnapshotModifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot KeyboardSnapshot
+		exp      Modifiers
+	}{
+		{name: "nothing down", snapshot: KeyboardSnapshot{}, exp: 0},
+		{name: "left shift", snapshot: snapshotWith(keyStateDown, VKLShift), exp: ModShift},
+		{name: "generic and sided keys", snapshot: snapshotWith(keyStateDown, VKControl, VKLControl, VKRControl), exp: ModControl},
+		{name: "all modifiers", snapshot: snapshotWith(keyStateDown, VKRShift, VKLControl, VKRMenu, VKRWin), exp: ModShift | ModControl | ModAlt | ModWin},
+		{name: "toggled is not down", snapshot: snapshotWith(keyStateToggled, VKLShift, VKLWin), exp: 0},
+		{name: "non-modifier keys", snapshot: snapshotWith(keyStateDown, VKCapital, VKNumLock, 0x41), exp: 0},
+	}
+
+	for _, test := range tests {
+		got := test.snapshot.Modifiers()
+		if got != test.exp {
+			t.Errorf("%s: got modifiers %q, expected %q", test.name, got, test.exp)
+		}
+	}
+}
+
+func TestKeyboardSnapshotModifierReleaseInputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot KeyboardSnapshot
+		exp      []Input
+	}{
+		{name: "nothing down", snapshot: KeyboardSnapshot{}, exp: nil},
+		{
+			name:     "generic keys are not released",
+			snapshot: snapshotWith(keyStateDown, VKShift, VKControl, VKMenu),
+			exp:      nil,
+		},
+		{
+			name:     "released in a fixed order",
+			snapshot: snapshotWith(keyStateDown, VKRWin, VKLMenu, VKRShift, VKLShift, VKRControl),
+			exp: []Input{
+				keyUp(VKLShift), keyUp(VKRShift), keyUp(VKRControl), keyUp(VKLMenu), keyUp(VKRWin),
+			},
+		},
+		{
+			name:     "toggled keys are not released",
+			snapshot: snapshotWith(keyStateToggled, VKLShift, VKLControl),
+			exp:      nil,
+		},
+		{
+			name:     "non-modifier keys are not released",
+			snapshot: snapshotWith(keyStateDown, 0x41, VKCapital, VKLWin),
+			exp:      []Input{keyUp(VKLWin)},
+		},
+	}
+
+	for _, test := range tests {
+		got := test.snapshot.ModifierReleaseInputs()
+		if !reflect.DeepEqual(inputValues(got), inputValues(test.exp)) {
+			t.Errorf("%s: got %+v, expected %+v", test.name, inputValues(got), inputValues(test.exp))
+		}
+	}
+}
+
+func TestKeyboardSnapshotToggled(t *testing.T) {
+	snapshot := snapshotWith(keyStateToggled, VKCapital, VKScroll)
+	snapshot[VKNumLock] = keyStateDown
+	snapshot[VKKana] = keyStateDown | keyStateToggled
+
+	tests := []struct {
+		vk         VirtualKey
+		expToggled bool
+		expDown    bool
+	}{
+		{vk: VKCapital, expToggled: true},
+		{vk: VKScroll, expToggled: true},
+		{vk: VKNumLock, expDown: true},
+		{vk: VKKana, expToggled: true, expDown: true},
+		{vk: 0x41},
+		{vk: 0x1FF},
+	}
+
+	for _, test := range tests {
+		if got := snapshot.Toggled(test.vk); got != test.expToggled {
+			t.Errorf("0x%X: toggled is %t, expected %t", uint16(test.vk), got, test.expToggled)
+		}
+
+		if got := snapshot.Down(test.vk); got != test.expDown {
+			t.Errorf("0x%X: down is %t, expected %t", uint16(test.vk), got, test.expDown)
+		}
+	}
+
+	exp := LockState{CapsLock: true, ScrollLock: true, KanaLock: true}
+	if got := snapshot.LockState(); got != exp {
+		t.Errorf("got lock state %+v, expected %+v", got, exp)
+	}
+}
@@ -0,0 +1,159 @@
+package user32util
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Lock key verification timing used by SetLockKey.
+const (
+	LockKeyVerifyTimeout = 500 * time.Millisecond
+	lockKeyPollInterval  = 10 * time.Millisecond
+)
+
+// LockKeys are the keys whose toggle state can be read and set using
+// GetLockState and SetLockKey.
+var LockKeys = []VirtualKey{VKCapital, VKNumLock, VKScroll, VKKana}
+
+// LockState is the toggle state of the lock keys.
+type LockState struct {
+	CapsLock   bool
+	NumLock    bool
+	ScrollLock bool
+	KanaLock   bool
+}
+
+// LockState returns the toggle state of the lock keys in the snapshot.
+func (o KeyboardSnapshot) LockState() LockState {
+	return LockState{
+		CapsLock:   o.Toggled(VKCapital),
+		NumLock:    o.Toggled(VKNumLock),
+		ScrollLock: o.Toggled(VKScroll),
+		KanaLock:   o.Toggled(VKKana),
+	}
+}
+
+// Keys returns the state as a map of lock keys to their toggle state,
+// suitable for passing to SetLockState (e.g., to restore a saved state).
+func (o LockState) Keys() map[VirtualKey]bool {
+	return map[VirtualKey]bool{
+		VKCapital: o.CapsLock,
+		VKNumLock: o.NumLock,
+		VKScroll:  o.ScrollLock,
+		VKKana:    o.KanaLock,
+	}
+}
+
+// GetLockState returns the current toggle state of the lock keys.
+func GetLockState(user32 *User32DLL) (LockState, error) {
+	snapshot, err := GetKeyboardSnapshot(user32)
+	if err != nil {
+		return LockState{}, err
+	}
+
+	return snapshot.LockState(), nil
+}
+
+// IsLockKeyOn returns true if the specified lock key (one of LockKeys)
+// is toggled on.
+func IsLockKeyOn(key VirtualKey, user32 *User32DLL) (bool, error) {
+	if !isLockKey(key) {
+		return false, fmt.Errorf("key 0x%X is not a lock key", uint16(key))
+	}
+
+	return lockKeyToggled(key, user32), nil
+}
+
+// SetLockKey sets the specified lock key (one of LockKeys) to the desired
+// toggle state. The key is only tapped if its state differs from on.
+// SetLockKey then waits up to LockKeyVerifyTimeout for the new state to
+// take effect, returning an error if it does not (e.g., when tapping
+// VKKana on a keyboard layout that does not support it).
+//
+// The returned bool is true if the key was tapped.
+func SetLockKey(key VirtualKey, on bool, user32 *User32DLL) (bool, error) {
+	if !isLockKey(key) {
+		return false, fmt.Errorf("key 0x%X is not a lock key", uint16(key))
+	}
+
+	if lockKeyToggled(key, user32) == on {
+		return false, nil
+	}
+
+	err := SendInputs([]Input{
+		NewKeybdInput(KeyDownInput(key)),
+		NewKeybdInput(KeyUpInput(key)),
+	}, user32)
+	if err != nil {
+		return false, fmt.Errorf("failed to tap lock key 0x%X - %w", uint16(key), err)
+	}
+
+	deadline := time.Now().Add(LockKeyVerifyTimeout)
+	for {
+		if lockKeyToggled(key, user32) == on {
+			return true, nil
+		}
+
+		if time.Now().After(deadline) {
+			return true, fmt.Errorf("lock key 0x%X did not change state after %s",
+				uint16(key), LockKeyVerifyTimeout)
+		}
+
+		time.Sleep(lockKeyPollInterval)
+	}
+}
+
+// SetLockState sets the lock keys in desired (each one of LockKeys) to
+// their specified toggle state. Lock keys that are not in desired are
+// left unchanged, meaning a caller can change only some of the keys
+// (e.g., map[VirtualKey]bool{VKCapital: false}). Use LockState.Keys to
+// set all of the lock keys. Refer to SetLockKey for more information.
+//
+// An error is returned before any key is tapped if desired contains
+// a key that is not a lock key.
+func SetLockState(desired map[VirtualKey]bool, user32 *User32DLL) error {
+	for key := range desired {
+		if !isLockKey(key) {
+			return fmt.Errorf("key 0x%X is not a lock key", uint16(key))
+		}
+	}
+
+	for _, key := range LockKeys {
+		on, ok := desired[key]
+		if !ok {
+			continue
+		}
+
+		_, err := SetLockKey(key, on, user32)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isLockKey(key VirtualKey) bool {
+	for _, lockKey := range LockKeys {
+		if key == lockKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lockKeyToggled returns true if the lock key is toggled on.
+func lockKeyToggled(key VirtualKey, user32 *User32DLL) bool {
+	// Calling GetKeyState synchronizes the thread's key state with
+	// the system. Refer to GetKeyboardSnapshot for more information.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	GetKeyState(0, user32)
+
+	_, toggled := GetKeyState(key, user32)
+
+	return toggled
+}
@@ -0,0 +1,44 @@
+package user32util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLockStateKeys(t *testing.T) {
+	state := LockState{CapsLock: true, ScrollLock: true}
+
+	exp := map[VirtualKey]bool{
+		VKCapital: true,
+		VKNumLock: false,
+		VKScroll:  true,
+		VKKana:    false,
+	}
+
+	got := state.Keys()
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %+v, expected %+v", got, exp)
+	}
+
+	for _, key := range LockKeys {
+		if _, ok := got[key]; !ok {
+			t.Fatalf("lock key 0x%X is missing", uint16(key))
+		}
+	}
+}
+
+func TestSetLockStateRejectsNonLockKeys(t *testing.T) {
+	// The keys are validated before user32 is used.
+	err := SetLockState(map[VirtualKey]bool{VKCapital: true, VKLShift: true}, nil)
+	if err == nil {
+		t.Fatal("expected an error for a key that is not a lock key")
+	}
+}
+
+func TestSetLockStateEmpty(t *testing.T) {
+	// No keys are changed, so user32 is not used.
+	err := SetLockState(map[VirtualKey]bool{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
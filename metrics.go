@@ -0,0 +1,167 @@
+package user32util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsSource is implemented by types that report ListenerStats, such as
+// the listeners and Hub.
+type StatsSource interface {
+	Stats() ListenerStats
+}
+
+// NewMetricsHandler returns an http.Handler that reports the stats of
+// the specified sources in the Prometheus text exposition format. Each
+// map key is used as the value of the "listener" label of the source's
+// metrics.
+//
+// The following metrics are reported:
+//	user32util_events_received_total{listener,message}
+//	user32util_events_blocked_total{listener}
+//	user32util_callback_duration_seconds{listener} (histogram)
+//	user32util_callback_duration_max_seconds{listener}
+//	user32util_hooks_timeout_seconds{listener}
+//	user32util_queue_depth{listener}
+//	user32util_queue_dropped_total{listener}
+//
+// The queue metrics are only reported for sources that deliver events
+// through queues (refer to ListenerStats.Queued), such as Hub.
+func NewMetricsHandler(sources map[string]StatsSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		buf := bufio.NewWriter(w)
+		WriteMetrics(buf, sources)
+		buf.Flush()
+	})
+}
+
+// WriteMetrics writes the stats of the specified sources to w in the
+// Prometheus text exposition format.
+//
+// Refer to NewMetricsHandler for more information.
+func WriteMetrics(w io.Writer, sources map[string]StatsSource) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]ListenerStats, len(names))
+	for i, name := range names {
+		stats[i] = sources[name].Stats()
+	}
+
+	writeMetricHeader(w, "user32util_events_received_total", "counter",
+		"Number of hook events received by message type.")
+	for i, name := range names {
+		messages := make([]uintptr, 0, len(stats[i].Received))
+		for message := range stats[i].Received {
+			messages = append(messages, message)
+		}
+		sort.Slice(messages, func(a, b int) bool {
+			return messages[a] < messages[b]
+		})
+
+		for _, message := range messages {
+			fmt.Fprintf(w, "user32util_events_received_total{listener=%s,message=%s} %d\n",
+				quoteLabel(name), quoteLabel(MessageName(message)), stats[i].Received[message])
+		}
+	}
+
+	writeMetricHeader(w, "user32util_events_blocked_total", "counter",
+		"Number of hook events blocked.")
+	for i, name := range names {
+		fmt.Fprintf(w, "user32util_events_blocked_total{listener=%s} %d\n",
+			quoteLabel(name), stats[i].Blocked)
+	}
+
+	writeMetricHeader(w, "user32util_callback_duration_seconds", "histogram",
+		"Time spent in hook callbacks.")
+	for i, name := range names {
+		histogram := stats[i].Callbacks
+		label := quoteLabel(name)
+
+		var cumulative uint64
+		for b, bound := range histogram.Bounds {
+			cumulative += histogram.Counts[b]
+			fmt.Fprintf(w, "user32util_callback_duration_seconds_bucket{listener=%s,le=\"%s\"} %d\n",
+				label, formatSeconds(bound), cumulative)
+		}
+
+		// The counters are read individually while callbacks update
+		// them, so Count may not match the buckets. Deriving +Inf and
+		// the count from the buckets keeps the histogram consistent.
+		if len(histogram.Counts) > len(histogram.Bounds) {
+			cumulative += histogram.Counts[len(histogram.Bounds)]
+		}
+
+		fmt.Fprintf(w, "user32util_callback_duration_seconds_bucket{listener=%s,le=\"+Inf\"} %d\n",
+			label, cumulative)
+		fmt.Fprintf(w, "user32util_callback_duration_seconds_sum{listener=%s} %s\n",
+			label, formatSeconds(histogram.Sum))
+		fmt.Fprintf(w, "user32util_callback_duration_seconds_count{listener=%s} %d\n",
+			label, cumulative)
+	}
+
+	writeMetricHeader(w, "user32util_callback_duration_max_seconds", "gauge",
+		"Longest time spent in a single hook callback.")
+	for i, name := range names {
+		fmt.Fprintf(w, "user32util_callback_duration_max_seconds{listener=%s} %s\n",
+			quoteLabel(name), formatSeconds(stats[i].MaxCallback))
+	}
+
+	writeMetricHeader(w, "user32util_hooks_timeout_seconds", "gauge",
+		"Configured LowLevelHooksTimeout, or zero if it is not configured.")
+	for i, name := range names {
+		fmt.Fprintf(w, "user32util_hooks_timeout_seconds{listener=%s} %s\n",
+			quoteLabel(name), formatSeconds(stats[i].HooksTimeout))
+	}
+
+	var queued []int
+	for i := range names {
+		if stats[i].Queued {
+			queued = append(queued, i)
+		}
+	}
+
+	if len(queued) == 0 {
+		return
+	}
+
+	writeMetricHeader(w, "user32util_queue_depth", "gauge",
+		"Number of events waiting to be read by subscribers.")
+	for _, i := range queued {
+		fmt.Fprintf(w, "user32util_queue_depth{listener=%s} %d\n",
+			quoteLabel(names[i]), stats[i].QueueDepth)
+	}
+
+	writeMetricHeader(w, "user32util_queue_dropped_total", "counter",
+		"Number of events dropped because a subscriber's buffer was full.")
+	for _, i := range queued {
+		fmt.Fprintf(w, "user32util_queue_dropped_total{listener=%s} %d\n",
+			quoteLabel(names[i]), stats[i].Dropped)
+	}
+}
+
+func writeMetricHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
@@ -0,0 +1,174 @@
+package user32util
+
+import (
+	"io"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeStatsSource ListenerStats
+
+func (o fakeStatsSource) Stats() ListenerStats {
+	return ListenerStats(o)
+}
+
+func scrapeMetrics(t *testing.T, sources map[string]StatsSource) (string, string) {
+	t.Helper()
+
+	server := httptest.NewServer(NewMetricsHandler(sources))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.Header.Get("Content-Type"), string(body)
+}
+
+// metricsSamplePattern matches a sample line of the text exposition
+// format: a metric name, optional labels and a value.
+var metricsSamplePattern = regexp.MustCompile(`^user32util_[a-z_]+\{([a-z]+="([^"\\]|\\.)*",?)*\} \S+$`)
+
+func TestMetricsHandlerTextFormat(t *testing.T) {
+	counts := make([]uint64, len(CallbackDurationBuckets)+1)
+	counts[0] = 3
+	counts[2] = 1
+	counts[len(counts)-1] = 1
+
+	sources := map[string]StatsSource{
+		"kb": fakeStatsSource{
+			Received: map[uintptr]uint64{
+				uintptr(WMKeyUp):   4,
+				uintptr(WMKeyDown): 5,
+			},
+			Blocked: 2,
+			Callbacks: DurationHistogram{
+				Bounds: CallbackDurationBuckets,
+				Counts: counts,
+				Count:  5,
+				Sum:    1500 * time.Millisecond,
+			},
+			MaxCallback:  1200 * time.Millisecond,
+			HooksTimeout: 300 * time.Millisecond,
+		},
+		`hub "main"`: fakeStatsSource{
+			Callbacks: DurationHistogram{
+				Bounds: CallbackDurationBuckets,
+				Counts: make([]uint64, len(CallbackDurationBuckets)+1),
+			},
+			Queued:     true,
+			QueueDepth: 7,
+			Dropped:    1,
+		},
+	}
+
+	contentType, body := scrapeMetrics(t, sources)
+
+	if contentType != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("got content type %q", contentType)
+	}
+
+	expLines := []string{
+		"# HELP user32util_events_received_total Number of hook events received by message type.",
+		"# TYPE user32util_events_received_total counter",
+		`user32util_events_received_total{listener="kb",message="WM_KEYDOWN"} 5`,
+		`user32util_events_received_total{listener="kb",message="WM_KEYUP"} 4`,
+		"# TYPE user32util_events_blocked_total counter",
+		`user32util_events_blocked_total{listener="hub \"main\""} 0`,
+		`user32util_events_blocked_total{listener="kb"} 2`,
+		"# TYPE user32util_callback_duration_seconds histogram",
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="0.0001"} 3`,
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="0.0005"} 3`,
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="0.001"} 4`,
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="1"} 4`,
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="+Inf"} 5`,
+		`user32util_callback_duration_seconds_sum{listener="kb"} 1.5`,
+		`user32util_callback_duration_seconds_count{listener="kb"} 5`,
+		`user32util_callback_duration_max_seconds{listener="kb"} 1.2`,
+		`user32util_hooks_timeout_seconds{listener="kb"} 0.3`,
+		"# TYPE user32util_queue_depth gauge",
+		`user32util_queue_depth{listener="hub \"main\""} 7`,
+		"# TYPE user32util_queue_dropped_total counter",
+		`user32util_queue_dropped_total{listener="hub \"main\""} 1`,
+	}
+
+	// The expected lines must appear in order.
+	remaining := body
+	for _, line := range expLines {
+		i := strings.Index(remaining, line+"\n")
+		if i < 0 {
+			t.Fatalf("missing or out of order line %q in:\n%s", line, body)
+		}
+
+		remaining = remaining[i+len(line)+1:]
+	}
+
+	if strings.Contains(body, `user32util_queue_depth{listener="kb"}`) ||
+		strings.Contains(body, `user32util_queue_dropped_total{listener="kb"}`) {
+		t.Fatalf("listener without a queue reported queue metrics:\n%s", body)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || (fields[1] != "HELP" && fields[1] != "TYPE") {
+				t.Fatalf("malformed comment line %q", line)
+			}
+			continue
+		}
+
+		if !metricsSamplePattern.MatchString(line) {
+			t.Fatalf("malformed sample line %q", line)
+		}
+	}
+}
+
+func TestMetricsHandlerOmitsQueueMetricsForListeners(t *testing.T) {
+	_, body := scrapeMetrics(t, map[string]StatsSource{
+		"mouse": fakeStatsSource{},
+	})
+
+	if strings.Contains(body, "user32util_queue_") {
+		t.Fatalf("expected no queue metrics for listeners:\n%s", body)
+	}
+}
+
+func TestMetricsHandlerHistogramIsConsistent(t *testing.T) {
+	counts := make([]uint64, len(CallbackDurationBuckets)+1)
+	counts[0] = 2
+	counts[len(CallbackDurationBuckets)-1] = 3
+	counts[len(counts)-1] = 1
+
+	// Count was read before the last observations reached the buckets.
+	_, body := scrapeMetrics(t, map[string]StatsSource{
+		"kb": fakeStatsSource{
+			Callbacks: DurationHistogram{
+				Bounds: CallbackDurationBuckets,
+				Counts: counts,
+				Count:  4,
+			},
+		},
+	})
+
+	expLines := []string{
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="1"} 5`,
+		`user32util_callback_duration_seconds_bucket{listener="kb",le="+Inf"} 6`,
+		`user32util_callback_duration_seconds_count{listener="kb"} 6`,
+	}
+
+	for _, line := range expLines {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("missing line %q in:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package user32util
+
+import (
+	"strings"
+	"sync"
+)
+
+// Modifiers is a set of modifier keys.
+type Modifiers uint8
+
+const (
+	ModShift Modifiers = 1 << iota
+	ModControl
+	ModAlt
+	ModWin
+)
+
+// Has returns true if all of the modifiers in mods are set.
+func (o Modifiers) Has(mods Modifiers) bool {
+	return o&mods == mods
+}
+
+func (o Modifiers) String() string {
+	var names []string
+
+	if o&ModControl != 0 {
+		names = append(names, "Ctrl")
+	}
+	if o&ModAlt != 0 {
+		names = append(names, "Alt")
+	}
+	if o&ModShift != 0 {
+		names = append(names, "Shift")
+	}
+	if o&ModWin != 0 {
+		names = append(names, "Win")
+	}
+
+	return strings.Join(names, "+")
+}
+
+// modifierOf returns the modifier that a virtual key represents, or zero
+// if it is not a modifier key.
+func modifierOf(vk VirtualKey) Modifiers {
+	switch vk {
+	case VKShift, VKLShift, VKRShift:
+		return ModShift
+	case VKControl, VKLControl, VKRControl:
+		return ModControl
+	case VKMenu, VKLMenu, VKRMenu:
+		return ModAlt
+	case VKLWin, VKRWin:
+		return ModWin
+	}
+
+	return 0
+}
+
+// modifierTracker tracks which modifier keys are held down using the
+// events reported by a keyboard hook. Left and right keys are tracked
+// separately so that releasing one does not clear the other.
+//
+// It is safe for concurrent use, which lets a Hub keep a single tracker
+// while its old and new hook threads overlap.
+type modifierTracker struct {
+	mu   sync.Mutex
+	held map[VirtualKey]bool
+}
+
+func newModifierTracker() *modifierTracker {
+	return &modifierTracker{
+		held: make(map[VirtualKey]bool),
+	}
+}
+
+// update records a keyboard event and returns the modifiers held after it.
+func (o *modifierTracker) update(event LowLevelKeyboardEvent) Modifiers {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	vk := VirtualKey(event.Struct.VkCode)
+	if modifierOf(vk) != 0 {
+		switch event.KeyboardButtonAction() {
+		case WMKeyDown, WHSystemKeyDown:
+			o.held[vk] = true
+		case WMKeyUp, WMSystemKeyUp:
+			delete(o.held, vk)
+		}
+	}
+
+	return o.currentLocked()
+}
+
+// current returns the modifiers that are currently held.
+func (o *modifierTracker) current() Modifiers {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.currentLocked()
+}
+
+// reset forgets all held modifiers.
+func (o *modifierTracker) reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.held = make(map[VirtualKey]bool)
+}
+
+func (o *modifierTracker) currentLocked() Modifiers {
+	var mods Modifiers
+	for vk := range o.held {
+		mods |= modifierOf(vk)
+	}
+
+	return mods
+}
@@ -0,0 +1,315 @@
+package user32util
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// MonitorFromPoint dwFlags values.
+const (
+	MonitorDefaultToNull    uint32 = 0x00000000
+	MonitorDefaultToPrimary uint32 = 0x00000001
+	MonitorDefaultToNearest uint32 = 0x00000002
+)
+
+const (
+	monitorInfoFPrimary  = 0x00000001
+	mdtEffectiveDpi      = 0
+	defaultDpi           = 96
+	shcoreDllName        = "shcore.dll"
+	getDpiForMonitorName = "GetDpiForMonitor"
+)
+
+// GetDpiForMonitor lives in shcore.dll rather than user32.dll.
+var shcore = windows.NewLazySystemDLL(shcoreDllName)
+
+// Monitor describes a single display monitor.
+//
+// Coordinates are reported in the DPI awareness context of the calling
+// process. Processes that are not per-monitor DPI aware may receive
+// scaled (virtualized) coordinates.
+type Monitor struct {
+	// Handle is the monitor's HMONITOR.
+	Handle uintptr
+
+	// Bounds is the monitor's display rectangle in virtual-screen
+	// coordinates.
+	Bounds Rect
+
+	// WorkArea is the portion of Bounds that is not covered by the
+	// taskbar or application desktop toolbars.
+	WorkArea Rect
+
+	// Primary is true if this is the primary monitor.
+	Primary bool
+
+	// DeviceName is the monitor's device name (e.g., "\\.\DISPLAY1").
+	DeviceName string
+
+	// DPI is the monitor's effective DPI. It is 96 if the DPI
+	// cannot be determined.
+	DPI uint32
+}
+
+// Scale returns the monitor's DPI scale factor (e.g., 1.5 for 144 DPI).
+func (o Monitor) Scale() float64 {
+	if o.DPI == 0 {
+		return 1
+	}
+
+	return float64(o.DPI) / defaultDpi
+}
+
+// Center returns the point at the center of the monitor.
+func (o Monitor) Center() Point {
+	return o.Bounds.Center()
+}
+
+// EnumDisplayMonitors returns the display monitors attached to
+// the desktop in the order reported by Windows.
+//
+// From the Windows API documentation:
+//	The EnumDisplayMonitors function enumerates display monitors
+//	(including invisible pseudo-monitors associated with the mirroring
+//	drivers) that intersect a region formed by the intersection of
+//	a specified clipping rectangle and the visible region of a device
+//	context.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-enumdisplaymonitors
+func EnumDisplayMonitors(user32 *User32DLL) ([]Monitor, error) {
+	id, handles := monitorEnums.start()
+	defer monitorEnums.finish(id)
+
+	ret, _, err := user32.proc(procEnumDisplayMonitors).Call(0, 0, monitorEnumCallback(), id)
+	if ret == 0 {
+		return nil, newCallError("enumerate display monitors", enumDisplayMonitorsName, err)
+	}
+
+	monitors := make([]Monitor, len(*handles))
+	for i, handle := range *handles {
+		monitors[i], err = GetMonitorInfo(handle, user32)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return monitors, nil
+}
+
+// MonitorFromPoint returns the monitor that contains p. The flags
+// parameter determines what happens when p is not on any monitor.
+// An error is returned if no monitor is found. If the procedure is
+// unavailable, the returned *CallError wraps ErrProcUnavailable.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-monitorfrompoint
+func MonitorFromPoint(p Point, flags uint32, user32 *User32DLL) (Monitor, error) {
+	args := append(pointArgs(p), uintptr(flags))
+
+	handle, _, err := user32.proc(procMonitorFromPoint).Call(args...)
+	if errors.Is(err, ErrProcUnavailable) {
+		return Monitor{}, newCallError("get monitor from point", monitorFromPointName, err)
+	}
+
+	if handle == 0 {
+		return Monitor{}, fmt.Errorf("no monitor found for point %+v", p)
+	}
+
+	return GetMonitorInfo(handle, user32)
+}
+
+// GetMonitorInfo returns information about the monitor identified
+// by the specified HMONITOR.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getmonitorinfow
+func GetMonitorInfo(handle uintptr, user32 *User32DLL) (Monitor, error) {
+	info := monitorInfoEx{}
+	info.CbSize = uint32(unsafe.Sizeof(info))
+
+	ret, _, err := user32.proc(procGetMonitorInfoW).Call(handle, uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return Monitor{}, newCallError("get monitor info", getMonitorInfoWName, err)
+	}
+
+	dpi, err := GetDpiForMonitor(handle)
+	if err != nil {
+		dpi = defaultDpi
+	}
+
+	return Monitor{
+		Handle:     handle,
+		Bounds:     info.RcMonitor,
+		WorkArea:   info.RcWork,
+		Primary:    info.DwFlags&monitorInfoFPrimary != 0,
+		DeviceName: windows.UTF16ToString(info.SzDevice[:]),
+		DPI:        dpi,
+	}, nil
+}
+
+// GetDpiForMonitor returns the effective DPI of the monitor identified
+// by the specified HMONITOR. It requires Windows 8.1 or newer. On older
+// versions, the returned error wraps ErrProcUnavailable.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/shellscalingapi/nf-shellscalingapi-getdpiformonitor
+func GetDpiForMonitor(handle uintptr) (uint32, error) {
+	proc := shcore.NewProc(getDpiForMonitorName)
+
+	err := proc.Find()
+	if err != nil {
+		return 0, fmt.Errorf("%s - %w", getDpiForMonitorName, ErrProcUnavailable)
+	}
+
+	var dpiX uint32
+	var dpiY uint32
+
+	hresult, _, _ := proc.Call(handle, mdtEffectiveDpi,
+		uintptr(unsafe.Pointer(&dpiX)), uintptr(unsafe.Pointer(&dpiY)))
+	if hresult != 0 {
+		return 0, fmt.Errorf("GetDpiForMonitor failed with HRESULT 0x%X", hresult)
+	}
+
+	return dpiX, nil
+}
+
+// GetDpiForWindow returns the DPI of the specified window. It requires
+// Windows 10 version 1607 or newer. On older versions, the returned error
+// wraps ErrProcUnavailable.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getdpiforwindow
+func GetDpiForWindow(hwnd uintptr, user32 *User32DLL) (uint32, error) {
+	err := user32.proc(procGetDpiForWindow).find()
+	if err != nil {
+		return 0, err
+	}
+
+	dpi, _, err := user32.proc(procGetDpiForWindow).Call(hwnd)
+	if dpi == 0 {
+		return 0, newCallError("get dpi for window", getDpiForWindowName, err)
+	}
+
+	return uint32(dpi), nil
+}
+
+// ScreenLayoutFromMonitors returns a ScreenLayout describing the specified
+// monitors. The layout's Scale is left as 1.
+func ScreenLayoutFromMonitors(monitors []Monitor) ScreenLayout {
+	var layout ScreenLayout
+
+	for i, monitor := range monitors {
+		if monitor.Primary {
+			layout.Primary = monitor.Bounds
+		}
+
+		if i == 0 {
+			layout.VirtualScreen = monitor.Bounds
+			continue
+		}
+
+		if monitor.Bounds.Left < layout.VirtualScreen.Left {
+			layout.VirtualScreen.Left = monitor.Bounds.Left
+		}
+		if monitor.Bounds.Top < layout.VirtualScreen.Top {
+			layout.VirtualScreen.Top = monitor.Bounds.Top
+		}
+		if monitor.Bounds.Right > layout.VirtualScreen.Right {
+			layout.VirtualScreen.Right = monitor.Bounds.Right
+		}
+		if monitor.Bounds.Bottom > layout.VirtualScreen.Bottom {
+			layout.VirtualScreen.Bottom = monitor.Bounds.Bottom
+		}
+	}
+
+	return layout
+}
+
+// From the Windows API documentation:
+//	The MONITORINFOEX structure contains information about
+//	a display monitor.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-monitorinfoexw
+type monitorInfoEx struct {
+	CbSize    uint32
+	RcMonitor Rect
+	RcWork    Rect
+	DwFlags   uint32
+	SzDevice  [32]uint16
+}
+
+// pointArgs returns the system call arguments needed to pass a POINT
+// structure by value. On 64-bit Windows, the structure is packed into
+// a single register. On 32-bit Windows, each field is pushed separately.
+func pointArgs(p Point) []uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return []uintptr{uintptr(uint64(uint32(p.X)) | uint64(uint32(p.Y))<<32)}
+	}
+
+	return []uintptr{uintptr(uint32(p.X)), uintptr(uint32(p.Y))}
+}
+
+// monitorEnums tracks in-progress calls to EnumDisplayMonitors.
+//
+// Callbacks created by windows.NewCallback are never freed, so a single
+// callback is shared by all calls. Each call is identified by the ID
+// passed to the callback in its dwData parameter.
+var monitorEnums = &monitorEnumRegistry{
+	calls: make(map[uintptr]*[]uintptr),
+}
+
+var (
+	monitorEnumCallbackOnce sync.Once
+	monitorEnumCallbackPtr  uintptr
+)
+
+func monitorEnumCallback() uintptr {
+	monitorEnumCallbackOnce.Do(func() {
+		monitorEnumCallbackPtr = windows.NewCallback(func(handle uintptr, hdc uintptr, rect uintptr, id uintptr) uintptr {
+			monitorEnums.add(id, handle)
+			return 1
+		})
+	})
+
+	return monitorEnumCallbackPtr
+}
+
+type monitorEnumRegistry struct {
+	mu     sync.Mutex
+	nextID uintptr
+	calls  map[uintptr]*[]uintptr
+}
+
+func (o *monitorEnumRegistry) start() (uintptr, *[]uintptr) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.nextID++
+	handles := &[]uintptr{}
+	o.calls[o.nextID] = handles
+
+	return o.nextID, handles
+}
+
+func (o *monitorEnumRegistry) add(id uintptr, handle uintptr) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	handles, ok := o.calls[id]
+	if ok {
+		*handles = append(*handles, handle)
+	}
+}
+
+func (o *monitorEnumRegistry) finish(id uintptr) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.calls, id)
+}
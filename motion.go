@@ -0,0 +1,293 @@
+package user32util
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// MotionCurve determines the path and velocity profile of a planned
+// mouse movement.
+type MotionCurve uint8
+
+const (
+	// MotionCurveLinear moves along a straight line at a constant speed.
+	MotionCurveLinear MotionCurve = iota
+
+	// MotionCurveMinimumJerk moves along a straight line using
+	// a minimum-jerk velocity profile, which accelerates and decelerates
+	// smoothly like a human arm.
+	MotionCurveMinimumJerk
+
+	// MotionCurveBezier moves along a randomly curved cubic Bezier path
+	// using a minimum-jerk velocity profile.
+	MotionCurveBezier
+)
+
+// Default MotionPlanner values.
+const (
+	DefaultMotionFittsA       = 100 * time.Millisecond
+	DefaultMotionFittsB       = 150 * time.Millisecond
+	DefaultMotionTargetWidth  = 16
+	DefaultMotionStepInterval = 8 * time.Millisecond
+)
+
+// MotionStep is a single step of a planned mouse movement.
+type MotionStep struct {
+	// Delay is the amount of time to wait before moving to Point.
+	Delay time.Duration
+
+	// Point is the cursor position after the step, in screen
+	// coordinates.
+	Point Point
+}
+
+// MotionPlanner turns a start and target point into a timed sequence of
+// mouse movements that resembles a human moving a mouse.
+//
+// The zero value moves in a straight line at a constant speed using the
+// default Fitts's law parameters. Planning is deterministic: the same
+// planner and points always produce the same steps.
+type MotionPlanner struct {
+	// Curve is the movement's path and velocity profile.
+	Curve MotionCurve
+
+	// FittsA and FittsB are the intercept and slope of the Fitts's law
+	// equation used to calculate the movement's duration:
+	//	duration = FittsA + FittsB * log2(distance / TargetWidth + 1)
+	// Zero values are replaced with DefaultMotionFittsA and
+	// DefaultMotionFittsB.
+	FittsA time.Duration
+	FittsB time.Duration
+
+	// TargetWidth is the width of the target in pixels. Zero is replaced
+	// with DefaultMotionTargetWidth.
+	TargetWidth float64
+
+	// StepInterval is the approximate amount of time between steps.
+	// Zero is replaced with DefaultMotionStepInterval.
+	StepInterval time.Duration
+
+	// Overshoot is the distance past the target that the movement travels
+	// before correcting, as a fraction of the total distance (e.g., 0.05).
+	// Zero disables overshooting.
+	Overshoot float64
+
+	// Jitter is the standard deviation, in pixels, of random noise added
+	// to each intermediate point. The final point is never jittered.
+	Jitter float64
+
+	// Seed seeds the random values used by the planner.
+	Seed int64
+}
+
+// Duration returns the Fitts's law duration of a movement between
+// two points.
+func (o MotionPlanner) Duration(from Point, to Point) time.Duration {
+	a := o.FittsA
+	if a == 0 {
+		a = DefaultMotionFittsA
+	}
+
+	b := o.FittsB
+	if b == 0 {
+		b = DefaultMotionFittsB
+	}
+
+	width := o.TargetWidth
+	if width <= 0 {
+		width = DefaultMotionTargetWidth
+	}
+
+	return a + time.Duration(float64(b)*math.Log2(distance(from, to)/width+1))
+}
+
+// Plan returns the steps needed to move the cursor from one point
+// to another. It returns nil if the points are the same.
+func (o MotionPlanner) Plan(from Point, to Point) []MotionStep {
+	if from == to {
+		return nil
+	}
+
+	rng := rand.New(rand.NewSource(o.Seed))
+
+	width := o.TargetWidth
+	if width <= 0 {
+		width = DefaultMotionTargetWidth
+	}
+
+	dist := distance(from, to)
+	if o.Overshoot <= 0 || dist*o.Overshoot < 1 || dist < 4*width {
+		return o.planSegment(from, to, rng, nil)
+	}
+
+	// Travel past the target, slightly off-axis, and then make
+	// a smaller corrective movement back to it.
+	ux := float64(to.X-from.X) / dist
+	uy := float64(to.Y-from.Y) / dist
+	past := dist * o.Overshoot
+	side := past * (rng.Float64() - 0.5)
+	overshoot := Point{
+		X: int32(math.Round(float64(to.X) + ux*past - uy*side)),
+		Y: int32(math.Round(float64(to.Y) + uy*past + ux*side)),
+	}
+
+	return o.planSegment(from, to, rng, o.planSegment(from, overshoot, rng, nil))
+}
+
+// planSegment appends the steps for a single movement between two
+// points to steps.
+func (o MotionPlanner) planSegment(from Point, to Point, rng *rand.Rand, steps []MotionStep) []MotionStep {
+	if len(steps) > 0 {
+		from = steps[len(steps)-1].Point
+	}
+
+	interval := o.StepInterval
+	if interval <= 0 {
+		interval = DefaultMotionStepInterval
+	}
+
+	duration := o.Duration(from, to)
+	numSteps := int(math.Ceil(float64(duration) / float64(interval)))
+	if numSteps < 1 {
+		numSteps = 1
+	}
+
+	path := linearPath(from, to)
+	if o.Curve == MotionCurveBezier {
+		path = bezierPath(from, to, rng)
+	}
+
+	var pendingDelay time.Duration
+	previous := from
+
+	for i := 1; i <= numSteps; i++ {
+		pendingDelay += duration / time.Duration(numSteps)
+
+		t := float64(i) / float64(numSteps)
+		if o.Curve != MotionCurveLinear {
+			t = minimumJerk(t)
+		}
+
+		x, y := path(t)
+		if i < numSteps && o.Jitter > 0 {
+			x += rng.NormFloat64() * o.Jitter
+			y += rng.NormFloat64() * o.Jitter
+		}
+
+		p := Point{X: int32(math.Round(x)), Y: int32(math.Round(y))}
+		if i == numSteps {
+			p = to
+		}
+
+		// Merge steps that do not move the cursor.
+		if p == previous && i < numSteps {
+			continue
+		}
+
+		steps = append(steps, MotionStep{
+			Delay: pendingDelay,
+			Point: p,
+		})
+
+		pendingDelay = 0
+		previous = p
+	}
+
+	return steps
+}
+
+// minimumJerk maps linear progress t (0 through 1) onto a minimum-jerk
+// position profile.
+func minimumJerk(t float64) float64 {
+	return t * t * t * (10 - 15*t + 6*t*t)
+}
+
+func linearPath(from Point, to Point) func(t float64) (float64, float64) {
+	return func(t float64) (float64, float64) {
+		return float64(from.X) + float64(to.X-from.X)*t,
+			float64(from.Y) + float64(to.Y-from.Y)*t
+	}
+}
+
+// bezierPath returns a cubic Bezier curve between two points whose
+// control points are randomly offset perpendicular to the straight
+// line between them.
+func bezierPath(from Point, to Point, rng *rand.Rand) func(t float64) (float64, float64) {
+	x0, y0 := float64(from.X), float64(from.Y)
+	x3, y3 := float64(to.X), float64(to.Y)
+	dx, dy := x3-x0, y3-y0
+
+	// Perpendicular offsets of up to 20% of the distance.
+	off1 := (rng.Float64() - 0.5) * 0.4
+	off2 := (rng.Float64() - 0.5) * 0.4
+
+	x1, y1 := x0+dx*0.3-dy*off1, y0+dy*0.3+dx*off1
+	x2, y2 := x0+dx*0.7-dy*off2, y0+dy*0.7+dx*off2
+
+	return func(t float64) (float64, float64) {
+		u := 1 - t
+		a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		return a*x0 + b*x1 + c*x2 + d*x3, a*y0 + b*y1 + c*y2 + d*y3
+	}
+}
+
+func distance(a Point, b Point) float64 {
+	return math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))
+}
+
+// RunMotion executes planned steps using relative mouse movements,
+// starting from the specified cursor position. Movements are sent with
+// MouseEventFMoveNoCoalesce so that Windows delivers every step.
+//
+// Relative movements are subject to the user's pointer speed and
+// acceleration settings. Use RunAbsoluteMotion to land on exact pixels.
+func RunMotion(ctx context.Context, from Point, steps []MotionStep, backend Backend) error {
+	current := from
+
+	for _, step := range steps {
+		err := sleepContext(ctx, step.Delay)
+		if err != nil {
+			return err
+		}
+
+		err = backend.SendInputs([]Input{NewMouseInput(MouseInput{
+			Dx:      step.Point.X - current.X,
+			Dy:      step.Point.Y - current.Y,
+			DwFlags: MouseEventFMove | MouseEventFMoveNoCoalesce,
+		})})
+		if err != nil {
+			return err
+		}
+
+		current = step.Point
+	}
+
+	return nil
+}
+
+// RunAbsoluteMotion executes planned steps using absolute mouse
+// movements normalized to the layout's virtual screen. Movements are sent
+// with MouseEventFMoveNoCoalesce so that Windows delivers every step.
+func RunAbsoluteMotion(ctx context.Context, steps []MotionStep, layout ScreenLayout, backend Backend) error {
+	for _, step := range steps {
+		input, err := layout.VirtualDeskMouseInput(step.Point)
+		if err != nil {
+			return err
+		}
+		input.DwFlags |= MouseEventFMoveNoCoalesce
+
+		err = sleepContext(ctx, step.Delay)
+		if err != nil {
+			return err
+		}
+
+		err = backend.SendInputs([]Input{NewMouseInput(input)})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
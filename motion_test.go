@@ -0,0 +1,102 @@
+package user32util
+
+import (
+	"testing"
+	"time"
+)
+
+// motionGolden is the output of motionGoldenPlanner. Update it only when
+// a change to the planner is meant to alter the planned paths.
+var motionGolden = []MotionStep{
+	{Delay: 39031165, Point: Point{X: -118, Y: 35}},
+	{Delay: 39031165, Point: Point{X: -115, Y: 34}},
+	{Delay: 39031165, Point: Point{X: -107, Y: 36}},
+	{Delay: 39031165, Point: Point{X: -96, Y: 31}},
+	{Delay: 39031165, Point: Point{X: -73, Y: 34}},
+	{Delay: 39031165, Point: Point{X: -47, Y: 33}},
+	{Delay: 39031165, Point: Point{X: -12, Y: 39}},
+	{Delay: 39031165, Point: Point{X: 21, Y: 50}},
+	{Delay: 39031165, Point: Point{X: 65, Y: 66}},
+	{Delay: 39031165, Point: Point{X: 111, Y: 87}},
+	{Delay: 39031165, Point: Point{X: 157, Y: 111}},
+	{Delay: 39031165, Point: Point{X: 202, Y: 139}},
+	{Delay: 39031165, Point: Point{X: 248, Y: 170}},
+	{Delay: 39031165, Point: Point{X: 290, Y: 196}},
+	{Delay: 39031165, Point: Point{X: 327, Y: 217}},
+	{Delay: 39031165, Point: Point{X: 363, Y: 237}},
+	{Delay: 39031165, Point: Point{X: 392, Y: 250}},
+	{Delay: 39031165, Point: Point{X: 415, Y: 261}},
+	{Delay: 39031165, Point: Point{X: 432, Y: 266}},
+	{Delay: 39031165, Point: Point{X: 444, Y: 271}},
+	{Delay: 39031165, Point: Point{X: 452, Y: 269}},
+	{Delay: 39031165, Point: Point{X: 454, Y: 273}},
+	{Delay: 39031165, Point: Point{X: 455, Y: 273}},
+	{Delay: 39604222, Point: Point{X: 454, Y: 274}},
+	{Delay: 39604222, Point: Point{X: 454, Y: 272}},
+	{Delay: 39604222, Point: Point{X: 446, Y: 269}},
+	{Delay: 39604222, Point: Point{X: 442, Y: 270}},
+	{Delay: 39604222, Point: Point{X: 434, Y: 266}},
+	{Delay: 39604222, Point: Point{X: 425, Y: 263}},
+	{Delay: 39604222, Point: Point{X: 416, Y: 263}},
+	{Delay: 39604222, Point: Point{X: 411, Y: 261}},
+	{Delay: 39604222, Point: Point{X: 411, Y: 262}},
+	{Delay: 39604222, Point: Point{X: 410, Y: 260}},
+}
+
+var motionGoldenPlanner = MotionPlanner{
+	Curve:        MotionCurveBezier,
+	StepInterval: 40 * time.Millisecond,
+	Overshoot:    0.08,
+	Jitter:       1.5,
+	Seed:         42,
+}
+
+func TestMotionPlannerGolden(t *testing.T) {
+	steps := motionGoldenPlanner.Plan(Point{X: -120, Y: 35}, Point{X: 410, Y: 260})
+
+	if len(steps) != len(motionGolden) {
+		t.Fatalf("got %d steps, expected %d - %+v", len(steps), len(motionGolden), steps)
+	}
+
+	for i := range steps {
+		if steps[i] != motionGolden[i] {
+			t.Fatalf("step %d is %+v, expected %+v", i, steps[i], motionGolden[i])
+		}
+	}
+}
+
+func TestMotionPlannerEndsOnTarget(t *testing.T) {
+	planners := []MotionPlanner{
+		{},
+		{Curve: MotionCurveMinimumJerk},
+		{Curve: MotionCurveBezier, Jitter: 3, Seed: 7},
+		{Curve: MotionCurveBezier, Overshoot: 0.1, Jitter: 2, Seed: 99},
+		{StepInterval: time.Second},
+	}
+
+	pairs := [][2]Point{
+		{{X: 0, Y: 0}, {X: 1, Y: 0}},
+		{{X: -500, Y: -300}, {X: 1919, Y: 1079}},
+		{{X: 800, Y: 600}, {X: 12, Y: 7}},
+		{{X: 100, Y: 100}, {X: 100, Y: 900}},
+	}
+
+	for i, planner := range planners {
+		for _, pair := range pairs {
+			steps := planner.Plan(pair[0], pair[1])
+			if len(steps) == 0 {
+				t.Fatalf("planner %d: no steps from %+v to %+v", i, pair[0], pair[1])
+			}
+
+			last := steps[len(steps)-1].Point
+			if last != pair[1] {
+				t.Fatalf("planner %d: last step from %+v to %+v is %+v",
+					i, pair[0], pair[1], last)
+			}
+		}
+	}
+
+	if steps := (MotionPlanner{}).Plan(Point{X: 5, Y: 5}, Point{X: 5, Y: 5}); steps != nil {
+		t.Fatalf("expected no steps for the same point, got %+v", steps)
+	}
+}
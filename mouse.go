@@ -1,6 +1,7 @@
 package user32util
 
 import (
+	"time"
 	"unsafe"
 )
 
@@ -13,6 +14,8 @@ const (
 	WMMouseHWheel MouseButtonAction = 0x020E
 	WMRButtonDown MouseButtonAction = 0x0204
 	WMRButtonUp   MouseButtonAction = 0x0205
+	WMMButtonDown MouseButtonAction = 0x0207
+	WMMButtonUp   MouseButtonAction = 0x0208
 )
 
 // Other mouse related message types (unsure where they are used, but they
@@ -26,6 +29,15 @@ const (
 	WMNCXButtonDblClk MouseButtonAction = 0x00AD
 )
 
+// MsllHookStruct Flags values.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-msllhookstruct
+const (
+	LLMHFInjected        uint32 = 0x01
+	LLMHFLowerILInjected uint32 = 0x02
+)
+
 // MouseButtonAction is an alias for the values contained in the
 // wParam field fo LowLevelKeyboardEvent.
 type MouseButtonAction uintptr
@@ -34,43 +46,130 @@ type MouseButtonAction uintptr
 // the LowLevelMouseProc Windows hook.
 //
 // Refer to LowLevelMouseEventListener for more information.
-func NewLowLevelMouseListener(fn OnLowLevelMouseEventFunc, user32 *User32DLL) (*LowLevelMouseEventListener, error) {
-	callBack := func(nCode int, wParam uintptr, lParam uintptr) {
-		if nCode == 0 {
-			fn(LowLevelMouseEvent{
-				WParam: wParam,
-				LParam: lParam,
-				Struct: (*MsllHookStruct)(unsafe.Pointer(lParam)),
-			})
-		}
-	}
+func NewLowLevelMouseListener(fn OnLowLevelMouseEventFunc, user32 *User32DLL, options ...ListenerOption) (*LowLevelMouseEventListener, error) {
+	config := newListenerConfig(options)
+
+	// Without a keyboard hook to track modifiers, they are queried
+	// using GetAsyncKeyState instead.
+	config.modifiers = nil
+	config.asyncModifiers = user32
 
-	handle, threadID, done, err := setWindowsHookExW(whMouseLl, callBack, user32)
+	hooks, err := setWindowsHookExW([]hookSpec{
+		{hookID: whMouseLl, callBack: newMouseHook(config, fn)},
+	}, config, user32)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LowLevelMouseEventListener{
-		user32:     user32,
-		hookHandle: handle,
-		threadID:   threadID,
-		fn:         fn,
-		done:       done,
+		user32: user32,
+		fn:     fn,
+		hooks:  hooks,
+		moves:  config.moves,
 	}, nil
 }
 
+// newMouseHook returns a hook callback that converts the hook's
+// parameters into a LowLevelMouseEvent, applies the listener's options,
+// and then calls fn.
+func newMouseHook(config listenerConfig, fn OnLowLevelMouseEventFunc) onHookCalledFunc {
+	clock := NewTickClock()
+
+	return func(nCode int, wParam uintptr, lParam uintptr) bool {
+		if nCode != 0 {
+			return false
+		}
+
+		event := LowLevelMouseEvent{
+			WParam: wParam,
+			LParam: lParam,
+			Struct: msllHookStruct(lParam),
+		}
+		event.Timestamp = clock.Time(event.Struct.Time)
+
+		// Querying modifiers takes several system calls, so they are
+		// only queried before filtering if the filter uses them.
+		queryModifiers := config.modifiers == nil && config.asyncModifiers != nil
+		if config.modifiers != nil {
+			event.Modifiers = config.modifiers.current()
+		} else if queryModifiers && config.filter.modifiers {
+			event.Modifiers = GetAsyncModifiers(config.asyncModifiers)
+			queryModifiers = false
+		}
+
+		if config.ignoreOwnInjections && event.IsOwnInjection() {
+			return false
+		}
+
+		if !config.filter.MatchMouse(event) {
+			return false
+		}
+
+		if queryModifiers {
+			event.Modifiers = GetAsyncModifiers(config.asyncModifiers)
+		}
+
+		if config.mouseBlocker != nil && config.mouseBlocker(event) {
+			return true
+		}
+
+		if config.moves != nil {
+			config.moves.mouse(event, fn)
+			return false
+		}
+
+		fn(event)
+
+		return false
+	}
+}
+
+// msllHookStruct converts the lParam of a LowLevelMouseProc into
+// a *MsllHookStruct. The struct is only valid during the hook callback.
+// Refer to kbdllHookStruct for why checkptr is disabled.
+//
+//go:nocheckptr
+func msllHookStruct(lParam uintptr) *MsllHookStruct {
+	return (*MsllHookStruct)(unsafe.Pointer(lParam))
+}
+
 type OnLowLevelMouseEventFunc func(event LowLevelMouseEvent)
 
 type LowLevelMouseEvent struct {
 	WParam uintptr
 	LParam uintptr
 	Struct *MsllHookStruct
+
+	// Timestamp is the time at which the event occurred, derived from
+	// Struct.Time. Refer to TickClock for more information.
+	Timestamp time.Time
+
+	// Modifiers are the modifier keys held when the event occurred.
+	// Listeners that also receive keyboard events (e.g., InputListener)
+	// report the modifiers observed by their keyboard hook. Other
+	// listeners query them using GetAsyncModifiers when the event is
+	// received, after it passes the listener's filter (unless the filter
+	// uses WithModifiers).
+	Modifiers Modifiers
 }
 
 func (o LowLevelMouseEvent) MouseButtonAction() MouseButtonAction {
 	return MouseButtonAction(o.WParam)
 }
 
+// IsInjected returns true if the event was injected by any process
+// (e.g., by calling SendInput).
+func (o LowLevelMouseEvent) IsInjected() bool {
+	return o.Struct.Flags&LLMHFInjected != 0
+}
+
+// IsOwnInjection returns true if the event was injected by this library.
+//
+// Refer to SetInjectionSignature for more information.
+func (o LowLevelMouseEvent) IsOwnInjection() bool {
+	return o.IsInjected() && isOwnExtraInfo(o.Struct.DwExtraInfo)
+}
+
 // From the Windows API documentation:
 //	Contains information about a low-level mouse input event.
 //
@@ -106,32 +205,49 @@ type Point struct {
 // Refer to the following Windows API document for more information:
 // https://docs.microsoft.com/en-us/previous-versions/windows/desktop/legacy/ms644986%28v=vs.85%29
 type LowLevelMouseEventListener struct {
-	user32     *User32DLL
-	fn         OnLowLevelMouseEventFunc
-	hookHandle uintptr
-	threadID   uint32
-	done       <-chan error
+	user32 *User32DLL
+	fn     OnLowLevelMouseEventFunc
+	hooks  *hookThread
+	moves  *moveCoalescer
 }
 
 // OnDone returns a channel that is written to when the event listener exits.
 // A non-nil error is written if an error caused the listener to exit.
 func (o *LowLevelMouseEventListener) OnDone() <-chan error {
-	return o.done
+	return o.hooks.done
+}
+
+// WatchdogEvents returns a channel that reports incidents detected by
+// the listener's watchdog. The channel is closed when the listener is
+// released. It returns nil if the listener was not created with the
+// WithWatchdog option.
+func (o *LowLevelMouseEventListener) WatchdogEvents() <-chan WatchdogEvent {
+	return o.hooks.watchdogEvents()
+}
+
+// Stats returns a snapshot of the listener's counters.
+func (o *LowLevelMouseEventListener) Stats() ListenerStats {
+	return o.hooks.stats.snapshot()
+}
+
+// MouseMoveStats returns counters describing how the listener coalesced
+// or sampled WMMouseMove events. The counters are zero unless the
+// CoalesceMouseMoves or SampleMouseMoves options are used.
+func (o *LowLevelMouseEventListener) MouseMoveStats() MouseMoveStats {
+	return o.moves.stats()
 }
 
 // Release releases the underlying hook handle and stops the listener from
 // receiving any additional events.
 func (o *LowLevelMouseEventListener) Release() error {
-	o.user32.postThreadMessageW.Call(uintptr(o.threadID), wmQuit, 0, 0)
-
-	o.user32.unhookWindowsHookEx.Call(o.hookHandle)
-
-	o.hookHandle = 0
+	o.hooks.release()
+	o.moves.close()
 
 	return nil
 }
 
-// SetCursorPos sets the mouse cursor position.
+// SetCursorPos sets the mouse cursor position. A *CallError is returned
+// if the call fails.
 //
 // From the Windows API documentation:
 // 	Moves the cursor to the specified screen coordinates. If the new
@@ -142,10 +258,10 @@ func (o *LowLevelMouseEventListener) Release() error {
 // Refer to the Windows API documentation for more information:
 // https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-setcursorpos
 func SetCursorPos(x int32, y int32, user32 *User32DLL) (bool, error) {
-	ret, _, err := user32.setCursorPos.Call(uintptr(x), uintptr(y))
-	if ret == 1 {
+	ret, _, err := user32.proc(procSetCursorPos).Call(uintptr(x), uintptr(y))
+	if ret != 0 {
 		return true, nil
 	}
 
-	return false, err
+	return false, newCallError("set cursor position", setCursorPosName, err)
 }
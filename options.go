@@ -0,0 +1,82 @@
+package user32util
+
+import (
+	"time"
+)
+
+// ListenerOption configures an input listener.
+type ListenerOption func(*listenerConfig)
+
+// IgnoreOwnInjections configures a listener to skip events that were
+// injected by this library. This prevents feedback loops when a listener's
+// callback sends input in response to the events that it receives.
+//
+// Refer to SetInjectionSignature for more information.
+func IgnoreOwnInjections() ListenerOption {
+	return func(config *listenerConfig) {
+		config.ignoreOwnInjections = true
+	}
+}
+
+// BlockKeyboardEvents configures a keyboard listener to call fn before
+// its callback. If fn returns true, the event is blocked: it is not passed
+// to other applications, and the listener's callback is not called.
+//
+// fn is called on the hook's thread. It must return quickly, otherwise
+// Windows may remove the hook. Refer to the LowLevelHooksTimeout
+// documentation for more information.
+func BlockKeyboardEvents(fn func(event LowLevelKeyboardEvent) bool) ListenerOption {
+	return func(config *listenerConfig) {
+		config.keyboardBlocker = fn
+	}
+}
+
+// BlockMouseEvents configures a mouse listener to call fn before its
+// callback. If fn returns true, the event is blocked: it is not passed to
+// other applications, and the listener's callback is not called.
+//
+// Refer to BlockKeyboardEvents for more information.
+func BlockMouseEvents(fn func(event LowLevelMouseEvent) bool) ListenerOption {
+	return func(config *listenerConfig) {
+		config.mouseBlocker = fn
+	}
+}
+
+// WithFilter configures a listener to only report events that match
+// the specified Filter. Events that do not match are passed to other
+// applications without reaching the listener's callback or blockers.
+func WithFilter(filter Filter) ListenerOption {
+	return func(config *listenerConfig) {
+		config.filter = filter
+	}
+}
+
+type listenerConfig struct {
+	ignoreOwnInjections bool
+	filter              Filter
+	modifiers           *modifierTracker
+	asyncModifiers      *User32DLL
+	moveInterval        time.Duration
+	moveSample          int
+	moves               *moveCoalescer
+	watchdog            *WatchdogConfig
+	stats               *hookStats
+	keyboardBlocker     func(event LowLevelKeyboardEvent) bool
+	mouseBlocker        func(event LowLevelMouseEvent) bool
+}
+
+func newListenerConfig(options []ListenerOption) listenerConfig {
+	config := listenerConfig{
+		modifiers: newModifierTracker(),
+	}
+
+	for _, option := range options {
+		option(&config)
+	}
+
+	if config.moveInterval > 0 || config.moveSample > 1 {
+		config.moves = newMoveCoalescer(config.moveInterval, config.moveSample)
+	}
+
+	return config
+}
@@ -0,0 +1,252 @@
+package user32util
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ErrProcUnavailable is returned when calling a procedure that does not
+// exist in the loaded DLL (e.g., because it was introduced in a newer
+// version of Windows). Use User32DLL.Capabilities to check for optional
+// procedures before calling them.
+var ErrProcUnavailable = errors.New("procedure is unavailable on this version of Windows")
+
+// procID identifies a procedure in user32Procs.
+type procID int
+
+const (
+	procSetWindowsHookExW procID = iota
+	procCallNextHookEx
+	procUnhookWindowsHookEx
+	procGetMessageW
+	procSendInput
+	procPostThreadMessageW
+	procSetCursorPos
+	procEnumDisplayMonitors
+	procGetMonitorInfoW
+	procMonitorFromPoint
+	procGetDpiForWindow
+	procGetDoubleClickTime
+	procGetSystemMetrics
+	procGetCursorPos
+	procGetPhysicalCursorPos
+	procClipCursor
+	procGetClipCursor
+	procShowCursor
+	procGetCursorInfo
+	procGetAsyncKeyState
+	procGetKeyState
+	procGetKeyboardState
+	procGetForegroundWindow
+	numProcs
+)
+
+// procSpec declares a procedure of the user32 DLL.
+type procSpec struct {
+	name string
+
+	// required procedures are resolved when the DLL is loaded, which
+	// fails if they are missing. Optional procedures are resolved when
+	// they are first called.
+	required bool
+}
+
+// user32Procs declares the user32 procedures used by this library.
+// To use a new procedure, add a procID and declare it here.
+//
+// Only the procedures needed to install hooks and send input are
+// required. The others are used by individual helpers, some of which
+// depend on the version of Windows, and are optional so that a missing
+// procedure only affects the helpers that use it.
+var user32Procs = [numProcs]procSpec{
+	procSetWindowsHookExW:   {name: setWindowsHookExWName, required: true},
+	procCallNextHookEx:      {name: callNextHookExName, required: true},
+	procUnhookWindowsHookEx: {name: unhookWindowsHookExName, required: true},
+	procGetMessageW:         {name: getMessageWName, required: true},
+	procSendInput:           {name: sendInputName, required: true},
+	procPostThreadMessageW:  {name: postThreadMessageWName, required: true},
+	procSetCursorPos:        {name: setCursorPosName, required: true},
+
+	procEnumDisplayMonitors: {name: enumDisplayMonitorsName},
+	procGetMonitorInfoW:     {name: getMonitorInfoWName},
+	procMonitorFromPoint:    {name: monitorFromPointName},
+	procGetDoubleClickTime:  {name: getDoubleClickTimeName},
+	procGetSystemMetrics:    {name: getSystemMetricsName},
+	procGetCursorPos:        {name: getCursorPosName},
+	procClipCursor:          {name: clipCursorName},
+	procGetClipCursor:       {name: getClipCursorName},
+	procShowCursor:          {name: showCursorName},
+	procGetCursorInfo:       {name: getCursorInfoName},
+	procGetAsyncKeyState:    {name: getAsyncKeyStateName},
+	procGetKeyState:         {name: getKeyStateName},
+	procGetKeyboardState:    {name: getKeyboardStateName},
+	procGetForegroundWindow: {name: getForegroundWindowName},
+
+	// GetDpiForWindow is only available on Windows 10 1607 and newer.
+	procGetDpiForWindow: {name: getDpiForWindowName},
+
+	// GetPhysicalCursorPos is only available on Windows Vista and newer.
+	procGetPhysicalCursorPos: {name: getPhysCursorPosName},
+}
+
+// systemProc declares a procedure of a system DLL other than user32.
+// These DLLs are loaded on demand by golang.org/x/sys/windows and are
+// never released.
+type systemProc struct {
+	dll  *windows.LazyDLL
+	spec procSpec
+}
+
+// systemProcs declares the procedures of other system DLLs used by this
+// library. They are reported by User32DLL.Capabilities.
+var systemProcs = []systemProc{
+	{dll: kernel32, spec: procSpec{name: getTickCountName, required: true}},
+	{dll: ntdll, spec: procSpec{name: rtlGetVersionName, required: true}},
+
+	// GetDpiForMonitor is only available on Windows 8.1 and newer.
+	{dll: shcore, spec: procSpec{name: getDpiForMonitorName}},
+}
+
+// lazyProc is a procedure that is resolved the first time it is used.
+type lazyProc struct {
+	dll    *windows.DLL
+	spec   procSpec
+	logger *slog.Logger
+	once   sync.Once
+	proc   *windows.Proc
+	err    error
+}
+
+func (o *lazyProc) find() error {
+	o.once.Do(func() {
+		o.proc, o.err = o.dll.FindProc(o.spec.name)
+		if o.err == nil {
+			o.logger.Debug("resolved procedure", "dll", o.dll.Name, "proc", o.spec.name)
+			return
+		}
+
+		if o.spec.required {
+			o.logger.Error("failed to resolve procedure",
+				"dll", o.dll.Name, "proc", o.spec.name, "error", o.err)
+		} else {
+			o.logger.Warn("optional procedure is unavailable",
+				"dll", o.dll.Name, "proc", o.spec.name, "error", o.err)
+		}
+
+		o.err = fmt.Errorf("%s - %w", o.spec.name, ErrProcUnavailable)
+	})
+
+	return o.err
+}
+
+// Call calls the procedure. If the procedure is unavailable, the
+// returned error wraps ErrProcUnavailable.
+func (o *lazyProc) Call(args ...uintptr) (uintptr, uintptr, error) {
+	err := o.find()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return o.proc.Call(args...)
+}
+
+// Capabilities describes the procedures and version of Windows that
+// are available to a User32DLL.
+type Capabilities struct {
+	// Version is the version of Windows.
+	Version WindowsVersion
+
+	// Procs lists the procedures that the library uses.
+	Procs []ProcCapability
+}
+
+// Available returns true if the named procedure is available.
+func (o Capabilities) Available(proc string) bool {
+	for _, capability := range o.Procs {
+		if capability.Name == proc {
+			return capability.Available
+		}
+	}
+
+	return false
+}
+
+// ProcCapability describes the availability of a single procedure.
+type ProcCapability struct {
+	DLL       string
+	Name      string
+	Required  bool
+	Available bool
+}
+
+// WindowsVersion is a Windows version number.
+type WindowsVersion struct {
+	Major uint32
+	Minor uint32
+	Build uint32
+}
+
+func (o WindowsVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", o.Major, o.Minor, o.Build)
+}
+
+// AtLeast returns true if the version is greater than or equal to
+// the specified version.
+func (o WindowsVersion) AtLeast(major uint32, minor uint32, build uint32) bool {
+	if o.Major != major {
+		return o.Major > major
+	}
+
+	if o.Minor != minor {
+		return o.Minor > minor
+	}
+
+	return o.Build >= build
+}
+
+const rtlGetVersionName = "RtlGetVersion"
+
+var ntdll = windows.NewLazySystemDLL("ntdll.dll")
+
+// osVersionInfo is the OSVERSIONINFOW structure.
+type osVersionInfo struct {
+	osVersionInfoSize uint32
+	majorVersion      uint32
+	minorVersion      uint32
+	buildNumber       uint32
+	platformID        uint32
+	csdVersion        [128]uint16
+}
+
+// GetWindowsVersion returns the version of Windows. Unlike GetVersionEx,
+// the result does not depend on the application's manifest.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows-hardware/drivers/ddi/wdm/nf-wdm-rtlgetversion
+func GetWindowsVersion() (WindowsVersion, error) {
+	proc := ntdll.NewProc(rtlGetVersionName)
+
+	err := proc.Find()
+	if err != nil {
+		return WindowsVersion{}, fmt.Errorf("%s - %w", rtlGetVersionName, ErrProcUnavailable)
+	}
+
+	info := osVersionInfo{}
+	info.osVersionInfoSize = uint32(unsafe.Sizeof(info))
+
+	status, _, _ := proc.Call(uintptr(unsafe.Pointer(&info)))
+	if status != 0 {
+		return WindowsVersion{}, fmt.Errorf("%s failed with NTSTATUS 0x%X", rtlGetVersionName, status)
+	}
+
+	return WindowsVersion{
+		Major: info.majorVersion,
+		Minor: info.minorVersion,
+		Build: info.buildNumber,
+	}, nil
+}
@@ -0,0 +1,266 @@
+package user32util
+
+import (
+	"time"
+)
+
+// DefaultLongPressDuration is the default amount of time that a button
+// must be held down to produce a ClickKindLongPress event.
+const DefaultLongPressDuration = 800 * time.Millisecond
+
+// ClickKind identifies the kind of a ClickEvent.
+type ClickKind uint8
+
+const (
+	ClickKindClick ClickKind = iota + 1
+	ClickKindDoubleClick
+	ClickKindTripleClick
+	ClickKindLongPress
+	ClickKindDragStart
+	ClickKindDragMove
+	ClickKindDragEnd
+)
+
+func (o ClickKind) String() string {
+	switch o {
+	case ClickKindClick:
+		return "click"
+	case ClickKindDoubleClick:
+		return "double-click"
+	case ClickKindTripleClick:
+		return "triple-click"
+	case ClickKindLongPress:
+		return "long-press"
+	case ClickKindDragStart:
+		return "drag-start"
+	case ClickKindDragMove:
+		return "drag-move"
+	case ClickKindDragEnd:
+		return "drag-end"
+	}
+
+	return "unknown"
+}
+
+// ClickEvent is a semantic mouse event produced by a ClickRecognizer.
+type ClickEvent struct {
+	Kind   ClickKind
+	Button MouseButton
+
+	// Point is the cursor position of the event that produced
+	// the ClickEvent.
+	Point Point
+
+	// Start is the cursor position at which the button was pressed.
+	Start Point
+
+	// Time is the tick count of the event that produced the ClickEvent.
+	// Refer to MsllHookStruct.Time for more information.
+	Time uint32
+
+	// Count is the number of consecutive clicks for click events
+	// (e.g., 2 for ClickKindDoubleClick). It is zero for other kinds.
+	Count int
+}
+
+// ClickRecognizerConfig configures a ClickRecognizer.
+type ClickRecognizerConfig struct {
+	// Metrics contains the double-click and drag thresholds. It is
+	// typically retrieved using GetClickMetrics.
+	Metrics ClickMetrics
+
+	// LongPressDuration is the amount of time a button must be held down
+	// without dragging to produce a ClickKindLongPress event instead of
+	// a click. Zero is replaced with DefaultLongPressDuration.
+	LongPressDuration time.Duration
+}
+
+// NewClickRecognizer creates a new ClickRecognizer that calls fn for
+// each semantic event that it recognizes.
+func NewClickRecognizer(config ClickRecognizerConfig, fn func(ClickEvent)) *ClickRecognizer {
+	if config.LongPressDuration == 0 {
+		config.LongPressDuration = DefaultLongPressDuration
+	}
+
+	return &ClickRecognizer{
+		config:  config,
+		fn:      fn,
+		buttons: make(map[MouseButton]*buttonState),
+	}
+}
+
+// ClickRecognizer turns the raw button and movement events reported by
+// a LowLevelMouseEventListener into clicks, double-clicks, triple-clicks,
+// long presses and drags.
+//
+// Recognition is based entirely on the events' coordinates and tick
+// counts, meaning recorded events produce the same results as live
+// events. Because a long press can only be distinguished from a click
+// once the button is released, ClickKindLongPress events are produced
+// on release.
+//
+// ClickRecognizer is not safe for concurrent use.
+type ClickRecognizer struct {
+	config  ClickRecognizerConfig
+	fn      func(ClickEvent)
+	buttons map[MouseButton]*buttonState
+}
+
+type buttonState struct {
+	pressed   bool
+	dragging  bool
+	downPoint Point
+	downTime  uint32
+
+	// Information about the previous click, used to recognize
+	// multi-clicks.
+	clicks        int
+	lastDownTime  uint32
+	lastDownPoint Point
+}
+
+// Process processes a single mouse event.
+func (o *ClickRecognizer) Process(event LowLevelMouseEvent) {
+	point := event.Struct.Point
+	tick := event.Struct.Time
+
+	if event.MouseButtonAction() == WMMouseMove {
+		o.move(point, tick)
+		return
+	}
+
+	button, down, ok := event.Button()
+	if !ok {
+		return
+	}
+
+	state, ok := o.buttons[button]
+	if !ok {
+		state = &buttonState{}
+		o.buttons[button] = state
+	}
+
+	if down {
+		o.down(button, state, point, tick)
+	} else {
+		o.up(button, state, point, tick)
+	}
+}
+
+func (o *ClickRecognizer) down(button MouseButton, state *buttonState, point Point, tick uint32) {
+	withinTime := time.Duration(tick-state.lastDownTime)*time.Millisecond <= o.config.Metrics.DoubleClickTime
+	withinRect := withinHalfRect(state.lastDownPoint, point,
+		o.config.Metrics.DoubleClickWidth, o.config.Metrics.DoubleClickHeight)
+
+	if !withinTime || !withinRect {
+		state.clicks = 0
+	}
+
+	state.pressed = true
+	state.dragging = false
+	state.downPoint = point
+	state.downTime = tick
+	state.lastDownTime = tick
+	state.lastDownPoint = point
+}
+
+func (o *ClickRecognizer) move(point Point, tick uint32) {
+	for _, button := range []MouseButton{MouseButtonLeft, MouseButtonRight, MouseButtonMiddle, MouseButtonX1, MouseButtonX2} {
+		state, ok := o.buttons[button]
+		if !ok || !state.pressed {
+			continue
+		}
+
+		if !state.dragging {
+			if withinHalfRect(state.downPoint, point, o.config.Metrics.DragWidth, o.config.Metrics.DragHeight) {
+				continue
+			}
+
+			state.dragging = true
+			state.clicks = 0
+			o.emit(ClickKindDragStart, button, state, state.downPoint, tick, 0)
+		}
+
+		o.emit(ClickKindDragMove, button, state, point, tick, 0)
+	}
+}
+
+func (o *ClickRecognizer) up(button MouseButton, state *buttonState, point Point, tick uint32) {
+	if !state.pressed {
+		return
+	}
+
+	state.pressed = false
+
+	if state.dragging {
+		state.dragging = false
+		o.emit(ClickKindDragEnd, button, state, point, tick, 0)
+		return
+	}
+
+	if time.Duration(tick-state.downTime)*time.Millisecond >= o.config.LongPressDuration {
+		state.clicks = 0
+		o.emit(ClickKindLongPress, button, state, point, tick, 0)
+		return
+	}
+
+	state.clicks++
+
+	switch state.clicks {
+	case 1:
+		o.emit(ClickKindClick, button, state, point, tick, 1)
+	case 2:
+		o.emit(ClickKindDoubleClick, button, state, point, tick, 2)
+	default:
+		o.emit(ClickKindTripleClick, button, state, point, tick, 3)
+		state.clicks = 0
+	}
+}
+
+func (o *ClickRecognizer) emit(kind ClickKind, button MouseButton, state *buttonState, point Point, tick uint32, count int) {
+	o.fn(ClickEvent{
+		Kind:   kind,
+		Button: button,
+		Point:  point,
+		Start:  state.downPoint,
+		Time:   tick,
+		Count:  count,
+	})
+}
+
+// withinHalfRect returns true if b is within a width by height rectangle
+// centered on a.
+func withinHalfRect(a Point, b Point, width int32, height int32) bool {
+	return abs32(b.X-a.X) <= width/2 && abs32(b.Y-a.Y) <= height/2
+}
+
+// Button returns the mouse button that the event presses or releases,
+// along with true if the event is a button press. The third return value
+// is false if the event is not a button event.
+func (o LowLevelMouseEvent) Button() (MouseButton, bool, bool) {
+	switch o.MouseButtonAction() {
+	case WMLButtonDown:
+		return MouseButtonLeft, true, true
+	case WMLButtonUp:
+		return MouseButtonLeft, false, true
+	case WMRButtonDown:
+		return MouseButtonRight, true, true
+	case WMRButtonUp:
+		return MouseButtonRight, false, true
+	case WMMButtonDown:
+		return MouseButtonMiddle, true, true
+	case WMMButtonUp:
+		return MouseButtonMiddle, false, true
+	case WMXButtonDown, WMXButtonUp:
+		down := o.MouseButtonAction() == WMXButtonDown
+
+		switch o.Struct.MouseData >> 16 {
+		case XButton1:
+			return MouseButtonX1, down, true
+		case XButton2:
+			return MouseButtonX2, down, true
+		}
+	}
+
+	return 0, false, false
+}
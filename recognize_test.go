@@ -0,0 +1,298 @@
+package user32util
+
+import (
+	"testing"
+	"time"
+)
+
+var recognizerTestConfig = ClickRecognizerConfig{
+	Metrics: ClickMetrics{
+		DoubleClickTime:   500 * time.Millisecond,
+		DoubleClickWidth:  4,
+		DoubleClickHeight: 4,
+		DragWidth:         4,
+		DragHeight:        4,
+	},
+	LongPressDuration: 800 * time.Millisecond,
+}
+
+type recognizerStep struct {
+	action MouseButtonAction
+	x      int32
+	y      int32
+	tick   uint32
+	data   uint32
+}
+
+func (o recognizerStep) event() LowLevelMouseEvent {
+	return LowLevelMouseEvent{
+		WParam: uintptr(o.action),
+		Struct: &MsllHookStruct{
+			Point:     Point{X: o.x, Y: o.y},
+			MouseData: o.data,
+			Time:      o.tick,
+		},
+	}
+}
+
+type recognizerResult struct {
+	kind   ClickKind
+	button MouseButton
+	count  int
+}
+
+func TestClickRecognizerBoundaries(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []recognizerStep
+		exp   []recognizerResult
+	}{
+		{
+			name: "single click",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 1000},
+				{action: WMLButtonUp, tick: 1050},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "double-click at the time limit",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 1000},
+				{action: WMLButtonUp, tick: 1050},
+				{action: WMLButtonDown, tick: 1500},
+				{action: WMLButtonUp, tick: 1550},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindDoubleClick, button: MouseButtonLeft, count: 2},
+			},
+		},
+		{
+			name: "double-click just after the time limit",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 1000},
+				{action: WMLButtonUp, tick: 1050},
+				{action: WMLButtonDown, tick: 1501},
+				{action: WMLButtonUp, tick: 1550},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "double-click at the edge of the rectangle",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, x: 100, y: 100, tick: 1000},
+				{action: WMLButtonUp, x: 100, y: 100, tick: 1050},
+				{action: WMLButtonDown, x: 102, y: 98, tick: 1100},
+				{action: WMLButtonUp, x: 102, y: 98, tick: 1150},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindDoubleClick, button: MouseButtonLeft, count: 2},
+			},
+		},
+		{
+			name: "double-click just outside of the rectangle",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, x: 100, y: 100, tick: 1000},
+				{action: WMLButtonUp, x: 100, y: 100, tick: 1050},
+				{action: WMLButtonDown, x: 100, y: 103, tick: 1100},
+				{action: WMLButtonUp, x: 100, y: 103, tick: 1150},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "double-click across tick count wraparound",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 0xFFFFFF00},
+				{action: WMLButtonUp, tick: 0xFFFFFF50},
+				{action: WMLButtonDown, tick: 0x00000010},
+				{action: WMLButtonUp, tick: 0x00000060},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindDoubleClick, button: MouseButtonLeft, count: 2},
+			},
+		},
+		{
+			name: "quadruple click restarts after a triple-click",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 1000},
+				{action: WMLButtonUp, tick: 1010},
+				{action: WMLButtonDown, tick: 1100},
+				{action: WMLButtonUp, tick: 1110},
+				{action: WMLButtonDown, tick: 1200},
+				{action: WMLButtonUp, tick: 1210},
+				{action: WMLButtonDown, tick: 1300},
+				{action: WMLButtonUp, tick: 1310},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+				{kind: ClickKindDoubleClick, button: MouseButtonLeft, count: 2},
+				{kind: ClickKindTripleClick, button: MouseButtonLeft, count: 3},
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "held just under the long press duration",
+			steps: []recognizerStep{
+				{action: WMRButtonDown, tick: 1000},
+				{action: WMRButtonUp, tick: 1799},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonRight, count: 1},
+			},
+		},
+		{
+			name: "held for the long press duration",
+			steps: []recognizerStep{
+				{action: WMRButtonDown, tick: 1000},
+				{action: WMRButtonUp, tick: 1800},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindLongPress, button: MouseButtonRight},
+			},
+		},
+		{
+			name: "long press resets the click count",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, tick: 1000},
+				{action: WMLButtonUp, tick: 1800},
+				{action: WMLButtonDown, tick: 1900},
+				{action: WMLButtonUp, tick: 1950},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindLongPress, button: MouseButtonLeft},
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "movement at the edge of the drag rectangle",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, x: 100, y: 100, tick: 1000},
+				{action: WMMouseMove, x: 98, y: 102, tick: 1010},
+				{action: WMLButtonUp, x: 98, y: 102, tick: 1020},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonLeft, count: 1},
+			},
+		},
+		{
+			name: "movement just outside of the drag rectangle",
+			steps: []recognizerStep{
+				{action: WMLButtonDown, x: 100, y: 100, tick: 1000},
+				{action: WMMouseMove, x: 97, y: 100, tick: 1010},
+				{action: WMMouseMove, x: 90, y: 100, tick: 1020},
+				{action: WMLButtonUp, x: 90, y: 100, tick: 1030},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindDragStart, button: MouseButtonLeft},
+				{kind: ClickKindDragMove, button: MouseButtonLeft},
+				{kind: ClickKindDragMove, button: MouseButtonLeft},
+				{kind: ClickKindDragEnd, button: MouseButtonLeft},
+			},
+		},
+		{
+			name: "drag is not a long press",
+			steps: []recognizerStep{
+				{action: WMMButtonDown, x: 0, y: 0, tick: 1000},
+				{action: WMMouseMove, x: 0, y: 10, tick: 1010},
+				{action: WMMButtonUp, x: 0, y: 10, tick: 5000},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindDragStart, button: MouseButtonMiddle},
+				{kind: ClickKindDragMove, button: MouseButtonMiddle},
+				{kind: ClickKindDragEnd, button: MouseButtonMiddle},
+			},
+		},
+		{
+			name: "x buttons are tracked separately",
+			steps: []recognizerStep{
+				{action: WMXButtonDown, tick: 1000, data: XButton1 << 16},
+				{action: WMXButtonUp, tick: 1010, data: XButton1 << 16},
+				{action: WMXButtonDown, tick: 1020, data: XButton2 << 16},
+				{action: WMXButtonUp, tick: 1030, data: XButton2 << 16},
+			},
+			exp: []recognizerResult{
+				{kind: ClickKindClick, button: MouseButtonX1, count: 1},
+				{kind: ClickKindClick, button: MouseButtonX2, count: 1},
+			},
+		},
+		{
+			name: "release without a press",
+			steps: []recognizerStep{
+				{action: WMLButtonUp, tick: 1000},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var results []recognizerResult
+
+		recognizer := NewClickRecognizer(recognizerTestConfig, func(event ClickEvent) {
+			results = append(results, recognizerResult{
+				kind:   event.Kind,
+				button: event.Button,
+				count:  event.Count,
+			})
+		})
+
+		for _, step := range test.steps {
+			recognizer.Process(step.event())
+		}
+
+		if len(results) != len(test.exp) {
+			t.Fatalf("%s: got %+v, expected %+v", test.name, results, test.exp)
+		}
+
+		for i := range test.exp {
+			if results[i] != test.exp[i] {
+				t.Fatalf("%s: got %+v, expected %+v", test.name, results, test.exp)
+			}
+		}
+	}
+}
+
+func TestClickRecognizerDragPoints(t *testing.T) {
+	var events []ClickEvent
+
+	recognizer := NewClickRecognizer(recognizerTestConfig, func(event ClickEvent) {
+		events = append(events, event)
+	})
+
+	steps := []recognizerStep{
+		{action: WMLButtonDown, x: 10, y: 10, tick: 100},
+		{action: WMMouseMove, x: 20, y: 10, tick: 110},
+		{action: WMLButtonUp, x: 25, y: 15, tick: 120},
+	}
+
+	for _, step := range steps {
+		recognizer.Process(step.event())
+	}
+
+	start := Point{X: 10, Y: 10}
+
+	exp := []ClickEvent{
+		{Kind: ClickKindDragStart, Button: MouseButtonLeft, Point: start, Start: start, Time: 110},
+		{Kind: ClickKindDragMove, Button: MouseButtonLeft, Point: Point{X: 20, Y: 10}, Start: start, Time: 110},
+		{Kind: ClickKindDragEnd, Button: MouseButtonLeft, Point: Point{X: 25, Y: 15}, Start: start, Time: 120},
+	}
+
+	if len(events) != len(exp) {
+		t.Fatalf("got %+v, expected %+v", events, exp)
+	}
+
+	for i := range exp {
+		if events[i] != exp[i] {
+			t.Fatalf("event %d is %+v, expected %+v", i, events[i], exp[i])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package user32util
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WheelDelta is the amount of wheel rotation reported for a single notch
+// of a standard mouse wheel. High-resolution wheels report smaller values.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/inputdev/wm-mousewheel
+const WheelDelta = 120
+
+// smoothScrollInterval is the amount of time between the individual
+// wheel inputs sent by SmoothScroll.
+const smoothScrollInterval = 10 * time.Millisecond
+
+// WheelInput returns a MouseInput that rotates the vertical wheel by the
+// specified delta. Positive values scroll forward (away from the user),
+// while negative values scroll backward. A delta of WheelDelta is one
+// notch.
+func WheelInput(delta int32) MouseInput {
+	return MouseInput{
+		MouseData: uint32(delta),
+		DwFlags:   MouseEventFWheel,
+	}
+}
+
+// HWheelInput returns a MouseInput that rotates the horizontal wheel by
+// the specified delta. Positive values scroll right, while negative values
+// scroll left. A delta of WheelDelta is one notch.
+func HWheelInput(delta int32) MouseInput {
+	return MouseInput{
+		MouseData: uint32(delta),
+		DwFlags:   MouseEventFHWheel,
+	}
+}
+
+// Scroll rotates the vertical wheel by the specified number of notches.
+// Positive values scroll forward (away from the user), while negative
+// values scroll backward. Applications typically scroll three lines
+// per notch.
+func Scroll(notches int32, backend Backend) error {
+	return backend.SendInputs([]Input{NewMouseInput(WheelInput(notches * WheelDelta))})
+}
+
+// HScroll rotates the horizontal wheel by the specified number of
+// notches. Positive values scroll right, while negative values scroll left.
+func HScroll(notches int32, backend Backend) error {
+	return backend.SendInputs([]Input{NewMouseInput(HWheelInput(notches * WheelDelta))})
+}
+
+// ScrollPixels emulates a high-resolution wheel by rotating the vertical
+// wheel by a fraction of a notch. The pixels value is converted into
+// a wheel delta assuming that a full notch scrolls pixelsPerNotch pixels.
+// An error is returned if pixelsPerNotch is not positive.
+//
+// Applications that do not support high-resolution wheels may ignore
+// the input until a full notch has accumulated.
+func ScrollPixels(pixels int32, pixelsPerNotch int32, backend Backend) error {
+	if pixelsPerNotch <= 0 {
+		return fmt.Errorf("pixels per notch must be positive, got %d", pixelsPerNotch)
+	}
+
+	delta := int32(roundDiv(int64(pixels)*WheelDelta, int64(pixelsPerNotch)))
+
+	return backend.SendInputs([]Input{NewMouseInput(WheelInput(delta))})
+}
+
+// SmoothScroll rotates the vertical wheel by the specified number of
+// notches, splitting the rotation into small deltas that are sent evenly
+// over the specified duration. The sum of the deltas is always exactly
+// notches * WheelDelta.
+func SmoothScroll(ctx context.Context, notches int32, duration time.Duration, backend Backend) error {
+	return smoothScroll(ctx, notches*WheelDelta, duration, WheelInput, backend)
+}
+
+// SmoothHScroll is the horizontal wheel equivalent of SmoothScroll.
+func SmoothHScroll(ctx context.Context, notches int32, duration time.Duration, backend Backend) error {
+	return smoothScroll(ctx, notches*WheelDelta, duration, HWheelInput, backend)
+}
+
+func smoothScroll(ctx context.Context, total int32, duration time.Duration, inputFn func(int32) MouseInput, backend Backend) error {
+	numSteps := int64(duration / smoothScrollInterval)
+	if numSteps < 1 {
+		numSteps = 1
+	}
+	if numSteps > int64(abs32(total)) && total != 0 {
+		numSteps = int64(abs32(total))
+	}
+
+	interval := duration / time.Duration(numSteps)
+	var sent int64
+
+	for i := int64(1); i <= numSteps; i++ {
+		if i > 1 {
+			err := sleepContext(ctx, interval)
+			if err != nil {
+				return err
+			}
+		}
+
+		target := roundDiv(int64(total)*i, numSteps)
+		delta := int32(target - sent)
+		if delta == 0 {
+			continue
+		}
+
+		err := backend.SendInputs([]Input{NewMouseInput(inputFn(delta))})
+		if err != nil {
+			return err
+		}
+
+		sent = target
+	}
+
+	return nil
+}
+
+// WheelDelta returns the signed wheel rotation of a WMMouseWheel or
+// WMMouseHWheel event. It returns zero for other events.
+func (o LowLevelMouseEvent) WheelDelta() int16 {
+	switch o.MouseButtonAction() {
+	case WMMouseWheel, WMMouseHWheel:
+		return int16(o.Struct.MouseData >> 16)
+	}
+
+	return 0
+}
+
+// WheelAccumulator converts the wheel deltas reported by a mouse listener
+// into whole notches. Fractional deltas reported by high-resolution wheels
+// are accumulated until they add up to a notch. The accumulated remainder
+// is discarded when the direction of rotation changes.
+//
+// The zero value is ready to use. It is not safe for concurrent use.
+type WheelAccumulator struct {
+	vertical   int32
+	horizontal int32
+}
+
+// Add accumulates the wheel delta of a mouse event and returns the number
+// of whole notches that it completes, along with true if the event is
+// for the horizontal wheel. Events that are not wheel events return zero.
+func (o *WheelAccumulator) Add(event LowLevelMouseEvent) (int32, bool) {
+	delta := int32(event.WheelDelta())
+	if delta == 0 {
+		return 0, false
+	}
+
+	horizontal := event.MouseButtonAction() == WMMouseHWheel
+
+	acc := &o.vertical
+	if horizontal {
+		acc = &o.horizontal
+	}
+
+	if (*acc > 0 && delta < 0) || (*acc < 0 && delta > 0) {
+		*acc = 0
+	}
+
+	*acc += delta
+	notches := *acc / WheelDelta
+	*acc -= notches * WheelDelta
+
+	return notches, horizontal
+}
+
+// Reset discards any accumulated remainders.
+func (o *WheelAccumulator) Reset() {
+	o.vertical = 0
+	o.horizontal = 0
+}
+
+// roundDiv divides a by b, rounding half away from zero. b must be
+// positive.
+func roundDiv(a int64, b int64) int64 {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+
+	return (a + b/2) / b
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
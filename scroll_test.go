@@ -0,0 +1,169 @@
+package user32util
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func wheelEvent(action MouseButtonAction, delta int16) LowLevelMouseEvent {
+	return LowLevelMouseEvent{
+		WParam: uintptr(action),
+		Struct: &MsllHookStruct{
+			MouseData: uint32(uint16(delta)) << 16,
+		},
+	}
+}
+
+func TestWheelAccumulator(t *testing.T) {
+	type add struct {
+		action        MouseButtonAction
+		delta         int16
+		expNotches    int32
+		expHorizontal bool
+	}
+
+	tests := []struct {
+		name string
+		adds []add
+	}{
+		{
+			name: "whole notches",
+			adds: []add{
+				{action: WMMouseWheel, delta: WheelDelta, expNotches: 1},
+				{action: WMMouseWheel, delta: -2 * WheelDelta, expNotches: -2},
+			},
+		},
+		{
+			name: "fractional deltas accumulate",
+			adds: []add{
+				{action: WMMouseWheel, delta: 40},
+				{action: WMMouseWheel, delta: 40},
+				{action: WMMouseWheel, delta: 50, expNotches: 1},
+				{action: WMMouseWheel, delta: 110, expNotches: 1},
+			},
+		},
+		{
+			name: "direction change discards the remainder",
+			adds: []add{
+				{action: WMMouseWheel, delta: 100},
+				{action: WMMouseWheel, delta: -30},
+				{action: WMMouseWheel, delta: -90, expNotches: -1},
+				{action: WMMouseWheel, delta: 100},
+			},
+		},
+		{
+			name: "wheels are independent",
+			adds: []add{
+				{action: WMMouseWheel, delta: 60},
+				{action: WMMouseHWheel, delta: -60, expHorizontal: true},
+				{action: WMMouseWheel, delta: 60, expNotches: 1},
+				{action: WMMouseHWheel, delta: -60, expNotches: -1, expHorizontal: true},
+			},
+		},
+		{
+			name: "non-wheel events are ignored",
+			adds: []add{
+				{action: WMMouseWheel, delta: 60},
+				{action: WMLButtonDown, delta: 120},
+				{action: WMMouseWheel, delta: 60, expNotches: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		acc := WheelAccumulator{}
+
+		for i, a := range test.adds {
+			notches, horizontal := acc.Add(wheelEvent(a.action, a.delta))
+			if notches != a.expNotches || horizontal != a.expHorizontal {
+				t.Fatalf("%s: add %d returned %d, %t - expected %d, %t",
+					test.name, i, notches, horizontal, a.expNotches, a.expHorizontal)
+			}
+		}
+	}
+}
+
+func TestWheelAccumulatorReset(t *testing.T) {
+	acc := WheelAccumulator{}
+	acc.Add(wheelEvent(WMMouseWheel, 100))
+	acc.Add(wheelEvent(WMMouseHWheel, 100))
+	acc.Reset()
+
+	notches, _ := acc.Add(wheelEvent(WMMouseWheel, 100))
+	if notches != 0 {
+		t.Fatalf("got %d notches after reset, expected 0", notches)
+	}
+
+	notches, _ = acc.Add(wheelEvent(WMMouseHWheel, 100))
+	if notches != 0 {
+		t.Fatalf("got %d horizontal notches after reset, expected 0", notches)
+	}
+}
+
+func TestScrollPixels(t *testing.T) {
+	tests := []struct {
+		pixels         int32
+		pixelsPerNotch int32
+		expDelta       int32
+	}{
+		{pixels: 40, pixelsPerNotch: 40, expDelta: WheelDelta},
+		{pixels: 10, pixelsPerNotch: 40, expDelta: 30},
+		{pixels: -10, pixelsPerNotch: 40, expDelta: -30},
+		{pixels: 1, pixelsPerNotch: 7, expDelta: 17},
+		{pixels: -1, pixelsPerNotch: 7, expDelta: -17},
+	}
+
+	for _, test := range tests {
+		backend := &fakeBackend{}
+
+		err := ScrollPixels(test.pixels, test.pixelsPerNotch, backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mouse, _ := backend.lastBatch()[0].MouseInput()
+		if int32(mouse.MouseData) != test.expDelta || mouse.DwFlags != MouseEventFWheel {
+			t.Fatalf("%d pixels at %d per notch sent delta %d flags 0x%X, expected %d",
+				test.pixels, test.pixelsPerNotch, int32(mouse.MouseData), mouse.DwFlags, test.expDelta)
+		}
+	}
+}
+
+func TestScrollPixelsRejectsInvalidPixelsPerNotch(t *testing.T) {
+	for _, pixelsPerNotch := range []int32{0, -40} {
+		backend := &fakeBackend{}
+
+		err := ScrollPixels(10, pixelsPerNotch, backend)
+		if err == nil {
+			t.Fatalf("expected an error for %d pixels per notch", pixelsPerNotch)
+		}
+
+		if len(backend.inputs()) > 0 {
+			t.Fatalf("expected no inputs for %d pixels per notch", pixelsPerNotch)
+		}
+	}
+}
+
+func TestSmoothScrollSendsExactTotal(t *testing.T) {
+	backend := &fakeBackend{}
+
+	err := SmoothHScroll(context.Background(), -3, 50*time.Millisecond, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total int32
+	for _, input := range backend.inputs() {
+		mouse, _ := input.MouseInput()
+		if mouse.DwFlags != MouseEventFHWheel {
+			t.Fatalf("got flags 0x%X, expected 0x%X", mouse.DwFlags, MouseEventFHWheel)
+		}
+
+		total += int32(mouse.MouseData)
+	}
+
+	if total != -3*WheelDelta {
+		t.Fatalf("sent a total delta of %d, expected %d", total, -3*WheelDelta)
+	}
+}
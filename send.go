@@ -32,6 +32,79 @@ const (
 	MouseEventFXUp            uint32 = 0x0100
 )
 
+// MouseInput mouseData values used with MouseEventFXDown and MouseEventFXUp.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-mouseinput
+const (
+	XButton1 uint32 = 0x0001
+	XButton2 uint32 = 0x0002
+)
+
+// MouseButton identifies a single mouse button.
+type MouseButton uint8
+
+const (
+	MouseButtonLeft MouseButton = iota + 1
+	MouseButtonRight
+	MouseButtonMiddle
+	MouseButtonX1
+	MouseButtonX2
+)
+
+// DownInput returns a MouseInput that presses the button.
+func (o MouseButton) DownInput() MouseInput {
+	switch o {
+	case MouseButtonLeft:
+		return MouseInput{DwFlags: MouseEventFLeftDown}
+	case MouseButtonRight:
+		return MouseInput{DwFlags: MouseEventFRightDown}
+	case MouseButtonMiddle:
+		return MouseInput{DwFlags: MouseEventFMiddleDown}
+	case MouseButtonX1:
+		return MouseInput{DwFlags: MouseEventFXDown, MouseData: XButton1}
+	case MouseButtonX2:
+		return MouseInput{DwFlags: MouseEventFXDown, MouseData: XButton2}
+	}
+
+	return MouseInput{}
+}
+
+// UpInput returns a MouseInput that releases the button.
+func (o MouseButton) UpInput() MouseInput {
+	switch o {
+	case MouseButtonLeft:
+		return MouseInput{DwFlags: MouseEventFLeftUp}
+	case MouseButtonRight:
+		return MouseInput{DwFlags: MouseEventFRightUp}
+	case MouseButtonMiddle:
+		return MouseInput{DwFlags: MouseEventFMiddleUp}
+	case MouseButtonX1:
+		return MouseInput{DwFlags: MouseEventFXUp, MouseData: XButton1}
+	case MouseButtonX2:
+		return MouseInput{DwFlags: MouseEventFXUp, MouseData: XButton2}
+	}
+
+	return MouseInput{}
+}
+
+func (o MouseButton) String() string {
+	switch o {
+	case MouseButtonLeft:
+		return "left"
+	case MouseButtonRight:
+		return "right"
+	case MouseButtonMiddle:
+		return "middle"
+	case MouseButtonX1:
+		return "x1"
+	case MouseButtonX2:
+		return "x2"
+	}
+
+	return fmt.Sprintf("unknown (%d)", o)
+}
+
 // Various KeybdInput dwFlags.
 //
 // Refer to the following Windows API document for more information:
@@ -58,16 +131,11 @@ type MouseInput struct {
 }
 
 // Wrapper for SendInput() that sends a single MouseInput.
+//
+// If input.DwExtraInfo is zero, it is set to the value returned by
+// InjectionSignature.
 func SendMouseInput(input MouseInput, user32 *User32DLL) error {
-	// Apparently, no byte padding is needed.
-	s := struct {
-		Type uint32
-		Val  MouseInput
-	}{
-		Type: InputMouse,
-		Val:  input,
-	}
-	return SendInput(1, unsafe.Pointer(&s), unsafe.Sizeof(s), user32)
+	return SendInputs([]Input{NewMouseInput(input)}, user32)
 }
 
 // From the Windows API documentation:
@@ -84,20 +152,11 @@ type KeybdInput struct {
 }
 
 // Wrapper for SendInput() that sends a single KeybdInput.
+//
+// If input.DwExtraInfo is zero, it is set to the value returned by
+// InjectionSignature.
 func SendKeydbInput(input KeybdInput, user32 *User32DLL) error {
-	// uint64's worth of padding needed to make Windows happy.
-	// This is so we can omit the other structs, thereby making go
-	// AND Windows happy.
-	s := struct {
-		Type uint32
-		Val  KeybdInput
-		Padd uint64
-	}{
-		Type: InputKeyboard,
-		Val:  input,
-		Padd: 0,
-	}
-	return SendInput(1, unsafe.Pointer(&s), unsafe.Sizeof(s), user32)
+	return SendInputs([]Input{NewKeybdInput(input)}, user32)
 }
 
 // From the Windows API documentation:
@@ -114,16 +173,108 @@ type HardwareInput struct {
 
 // No idea if this works. Untested.
 func SendHardwareInput(input HardwareInput, user32 *User32DLL) error {
-	s := struct {
-		Type uint32
-		Val  HardwareInput
-		Padd uint64
-	}{
+	return SendInputs([]Input{NewHardwareInput(input)}, user32)
+}
+
+// Input represents a single INPUT structure, which contains
+// a MouseInput, a KeybdInput, or a HardwareInput.
+//
+// Go does not support unions. Instead, the struct reserves enough space
+// for its largest member (MouseInput), which gives it the same size and
+// alignment as the Windows structure.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-input
+type Input struct {
+	Type  uint32
+	union MouseInput
+}
+
+// NewMouseInput returns an Input containing a MouseInput.
+func NewMouseInput(input MouseInput) Input {
+	return Input{
+		Type:  InputMouse,
+		union: input,
+	}
+}
+
+// NewKeybdInput returns an Input containing a KeybdInput.
+func NewKeybdInput(input KeybdInput) Input {
+	i := Input{
+		Type: InputKeyboard,
+	}
+	*(*KeybdInput)(unsafe.Pointer(&i.union)) = input
+	return i
+}
+
+// NewHardwareInput returns an Input containing a HardwareInput.
+func NewHardwareInput(input HardwareInput) Input {
+	i := Input{
 		Type: InputHardware,
-		Val:  input,
-		Padd: 0,
 	}
-	return SendInput(1, unsafe.Pointer(&s), unsafe.Sizeof(s), user32)
+	*(*HardwareInput)(unsafe.Pointer(&i.union)) = input
+	return i
+}
+
+// MouseInput returns the Input's MouseInput. The second return value is
+// false if the Input does not contain a MouseInput.
+func (o Input) MouseInput() (MouseInput, bool) {
+	if o.Type != InputMouse {
+		return MouseInput{}, false
+	}
+
+	return o.union, true
+}
+
+// KeybdInput returns the Input's KeybdInput. The second return value is
+// false if the Input does not contain a KeybdInput.
+func (o Input) KeybdInput() (KeybdInput, bool) {
+	if o.Type != InputKeyboard {
+		return KeybdInput{}, false
+	}
+
+	return *(*KeybdInput)(unsafe.Pointer(&o.union)), true
+}
+
+// HardwareInput returns the Input's HardwareInput. The second return
+// value is false if the Input does not contain a HardwareInput.
+func (o Input) HardwareInput() (HardwareInput, bool) {
+	if o.Type != InputHardware {
+		return HardwareInput{}, false
+	}
+
+	return *(*HardwareInput)(unsafe.Pointer(&o.union)), true
+}
+
+// SendInputs sends several inputs using a single call to SendInput.
+// Inputs are inserted into the input stream serially, meaning they are
+// not interspersed with other keyboard or mouse input.
+//
+// The DwExtraInfo field of MouseInput and KeybdInput values is set to the
+// value returned by InjectionSignature if it is zero. The caller's slice
+// is not modified.
+func SendInputs(inputs []Input, user32 *User32DLL) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	signed := make([]Input, len(inputs))
+	copy(signed, inputs)
+
+	for i := range signed {
+		switch signed[i].Type {
+		case InputMouse:
+			signed[i].union.DwExtraInfo = signExtraInfo(signed[i].union.DwExtraInfo)
+		case InputKeyboard:
+			kb := (*KeybdInput)(unsafe.Pointer(&signed[i].union))
+			kb.DwExtraInfo = signExtraInfo(kb.DwExtraInfo)
+		}
+	}
+
+	user32.logger.Debug("sending inputs",
+		"inputs", inputsLogValue{inputs: signed, keystrokes: user32.logKeystrokes})
+
+	return SendInput(uint(len(signed)), unsafe.Pointer(&signed[0]), unsafe.Sizeof(signed[0]), user32)
 }
 
 // SendInput is a hacky implementation of SendInput that works around the
@@ -132,21 +283,28 @@ func SendHardwareInput(input HardwareInput, user32 *User32DLL) error {
 // https://github.com/JamesHovious/w32/blob/master/user32.go works around this
 // by using cgo. I have no desire to make cgo a dependency of the project.
 //
+// If fewer than numInputs inputs are sent, a *SendInputError is returned.
+//
 // From the Windows API documentation:
 //	Synthesizes keystrokes, mouse motions, and button clicks.
 //
 // Refer to the following Windows API document for more information:
 // https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-sendinput
 func SendInput(numInputs uint, unsafePointerToVal unsafe.Pointer, inputStructSizeBytes uintptr, user32 *User32DLL) error {
-	numSent, _, err := user32.sendInput.Call(
+	numSent, _, err := user32.proc(procSendInput).Call(
 		uintptr(numInputs),
 		uintptr(unsafePointerToVal),
 		uintptr(inputStructSizeBytes))
 	if uint(numSent) == numInputs {
 		return nil
-	} else if err != nil {
-		return err
 	}
 
-	return fmt.Errorf("failed to send input, unknown errror")
+	user32.logger.Warn("failed to send all inputs",
+		"sent", uint(numSent), "requested", numInputs, "error", err)
+
+	return &SendInputError{
+		Sent:      uint(numSent),
+		Requested: numInputs,
+		Err:       newCallError("send input", sendInputName, err),
+	}
 }
@@ -0,0 +1,186 @@
+package user32util
+
+import (
+	"context"
+	"time"
+)
+
+// NewSequence returns an empty Sequence.
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+// Sequence builds a series of keyboard and mouse inputs, cursor movements
+// and waits that can be executed against a Backend. Consecutive inputs are
+// sent together using a single call to SendInput.
+//
+// For example, the following moves the cursor and clicks the left
+// mouse button:
+//	err := user32util.NewSequence().
+//		MoveTo(1221, 244).
+//		Click(user32util.MouseButtonLeft).
+//		Run(ctx, user32util.NewUser32Backend(user32))
+type Sequence struct {
+	steps []sequenceStep
+}
+
+// sequenceStep is either a batch of inputs, a wait or a cursor movement.
+type sequenceStep struct {
+	inputs []Input
+	wait   time.Duration
+	moveTo *Point
+}
+
+// Press presses a key.
+func (o *Sequence) Press(vk VirtualKey) *Sequence {
+	return o.appendInputs(NewKeybdInput(KeyDownInput(vk)))
+}
+
+// Release releases a key.
+func (o *Sequence) Release(vk VirtualKey) *Sequence {
+	return o.appendInputs(NewKeybdInput(KeyUpInput(vk)))
+}
+
+// Tap presses and then releases a key.
+func (o *Sequence) Tap(vk VirtualKey) *Sequence {
+	return o.Press(vk).Release(vk)
+}
+
+// Hold presses a key, waits for the specified duration, and then
+// releases the key.
+func (o *Sequence) Hold(vk VirtualKey, d time.Duration) *Sequence {
+	return o.Press(vk).Wait(d).Release(vk)
+}
+
+// Type types the specified text. Characters are sent as Unicode
+// inputs, meaning the text is typed regardless of the active
+// keyboard layout.
+func (o *Sequence) Type(text string) *Sequence {
+	var previous rune
+
+	for _, r := range text {
+		if r == '\n' && previous == '\r' {
+			previous = r
+			continue
+		}
+		previous = r
+
+		for _, input := range charInputs(r, false) {
+			o.appendInputs(NewKeybdInput(input))
+		}
+
+		for _, input := range charInputs(r, true) {
+			o.appendInputs(NewKeybdInput(input))
+		}
+	}
+
+	return o
+}
+
+// MoveTo moves the mouse cursor to the specified screen coordinates.
+func (o *Sequence) MoveTo(x int32, y int32) *Sequence {
+	o.steps = append(o.steps, sequenceStep{
+		moveTo: &Point{X: x, Y: y},
+	})
+
+	return o
+}
+
+// Click presses and then releases a mouse button.
+func (o *Sequence) Click(button MouseButton) *Sequence {
+	return o.appendInputs(NewMouseInput(button.DownInput()), NewMouseInput(button.UpInput()))
+}
+
+// Scroll rotates the vertical mouse wheel by the specified number of
+// notches. Positive values scroll forward (away from the user), while
+// negative values scroll backward.
+func (o *Sequence) Scroll(notches int32) *Sequence {
+	return o.appendInputs(NewMouseInput(WheelInput(notches * WheelDelta)))
+}
+
+// HScroll rotates the horizontal mouse wheel by the specified number of
+// notches. Positive values scroll right, while negative values scroll left.
+func (o *Sequence) HScroll(notches int32) *Sequence {
+	return o.appendInputs(NewMouseInput(HWheelInput(notches * WheelDelta)))
+}
+
+// Wait pauses the sequence for the specified duration.
+func (o *Sequence) Wait(d time.Duration) *Sequence {
+	o.steps = append(o.steps, sequenceStep{
+		wait: d,
+	})
+
+	return o
+}
+
+func (o *Sequence) appendInputs(inputs ...Input) *Sequence {
+	if len(o.steps) > 0 {
+		last := &o.steps[len(o.steps)-1]
+		if len(last.inputs) > 0 {
+			last.inputs = append(last.inputs, inputs...)
+			return o
+		}
+	}
+
+	o.steps = append(o.steps, sequenceStep{
+		inputs: inputs,
+	})
+
+	return o
+}
+
+// Run executes the sequence using the provided Backend.
+//
+// If ctx is cancelled or an error occurs before the sequence finishes,
+// every key and mouse button pressed by the sequence, but not yet released,
+// is released before Run returns. Keys that are still pressed when the
+// sequence finishes successfully are left pressed.
+func (o *Sequence) Run(ctx context.Context, backend Backend) error {
+	var tracker pressTracker
+
+	err := o.run(ctx, backend, &tracker)
+	if err != nil {
+		tracker.releaseUsing(backend)
+		return err
+	}
+
+	return nil
+}
+
+func (o *Sequence) run(ctx context.Context, backend Backend, tracker *pressTracker) error {
+	for _, step := range o.steps {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(step.inputs) > 0:
+			err = backend.SendInputs(step.inputs)
+			tracker.inputs(step.inputs)
+		case step.moveTo != nil:
+			err = backend.SetCursorPos(step.moveTo.X, step.moveTo.Y)
+		case step.wait > 0:
+			err = sleepContext(ctx, step.wait)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sleepContext sleeps for the specified duration or until ctx is done,
+// whichever happens first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
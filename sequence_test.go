@@ -0,0 +1,201 @@
+package user32util
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func keyDown(vk VirtualKey) Input {
+	return NewKeybdInput(KeyDownInput(vk))
+}
+
+func keyUp(vk VirtualKey) Input {
+	return NewKeybdInput(KeyUpInput(vk))
+}
+
+func unicodeInput(unit uint16, up bool) Input {
+	input := KeybdInput{WScan: unit, DwFlags: KeyEventFUnicode}
+	if up {
+		input.DwFlags |= KeyEventFKeyUp
+	}
+
+	return NewKeybdInput(input)
+}
+
+func TestSequenceBatchesInputs(t *testing.T) {
+	backend := &fakeBackend{}
+
+	err := NewSequence().
+		Tap('A').
+		Tap('B').
+		MoveTo(10, -20).
+		Click(MouseButtonLeft).
+		Run(context.Background(), backend)
+	if err != nil {
+		t.Fatalf("run failed - %s", err)
+	}
+
+	expBatches := [][]Input{
+		{keyDown('A'), keyUp('A'), keyDown('B'), keyUp('B')},
+		{NewMouseInput(MouseButtonLeft.DownInput()), NewMouseInput(MouseButtonLeft.UpInput())},
+	}
+	if !reflect.DeepEqual(batchValues(backend.batches), batchValues(expBatches)) {
+		t.Fatalf("got batches %+v, expected %+v", backend.batches, expBatches)
+	}
+
+	expMoves := []Point{{X: 10, Y: -20}}
+	if !reflect.DeepEqual(backend.moves, expMoves) {
+		t.Fatalf("got moves %+v, expected %+v", backend.moves, expMoves)
+	}
+}
+
+func TestSequenceSuccessLeavesKeysPressed(t *testing.T) {
+	backend := &fakeBackend{}
+
+	err := NewSequence().Press(VKLShift).Run(context.Background(), backend)
+	if err != nil {
+		t.Fatalf("run failed - %s", err)
+	}
+
+	exp := [][]Input{{keyDown(VKLShift)}}
+	if !reflect.DeepEqual(batchValues(backend.batches), batchValues(exp)) {
+		t.Fatalf("got batches %+v, expected %+v", backend.batches, exp)
+	}
+}
+
+func TestSequenceReleasesHeldInputsOnError(t *testing.T) {
+	moveErr := errors.New("move failed")
+	backend := &fakeBackend{moveErr: moveErr}
+
+	err := NewSequence().
+		Press(VKLShift).
+		Press('A').
+		Release('A').
+		Press(VKRControl).
+		Click(MouseButtonX1).
+		Press(VKLWin).
+		MoveTo(1, 1).
+		Tap('B').
+		Run(context.Background(), backend)
+	if !errors.Is(err, moveErr) {
+		t.Fatalf("got error %v, expected %v", err, moveErr)
+	}
+
+	// Keys are released in reverse press order. Released keys and
+	// clicked buttons are not released again.
+	exp := []Input{
+		keyUp(VKLWin),
+		keyUp(VKRControl),
+		keyUp(VKLShift),
+	}
+	if got := backend.lastBatch(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("got release batch %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+
+	for _, input := range backend.inputs() {
+		if kb, ok := input.KeybdInput(); ok && kb.WVK == 'B' {
+			t.Fatalf("input after the failed step was sent: %+v", kb)
+		}
+	}
+}
+
+func TestSequenceReleasesHeldKeysOnSendError(t *testing.T) {
+	sendErr := errors.New("send failed")
+	backend := &fakeBackend{sendErr: sendErr, failSend: 1}
+
+	err := NewSequence().
+		Press(VKLMenu).
+		Wait(time.Millisecond).
+		Tap('C').
+		Run(context.Background(), backend)
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("got error %v, expected %v", err, sendErr)
+	}
+
+	// The failed batch is recorded as if it was sent. It presses and
+	// releases 'C', so only the Alt key is still held.
+	exp := []Input{keyUp(VKLMenu)}
+	if got := backend.lastBatch(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("got release batch %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
+
+func TestSequenceReleasesHeldInputsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend := &fakeBackend{}
+	backend.onSend = func(batch []Input) {
+		if len(batch) == 2 {
+			cancel()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewSequence().
+			Press(VKLShift).
+			appendInputs(NewMouseInput(MouseButtonLeft.DownInput())).
+			Wait(time.Hour).
+			Release(VKLShift).
+			Run(ctx, backend)
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sequence did not stop after its context was cancelled")
+	}
+
+	exp := []Input{keyUp(VKLShift), NewMouseInput(MouseButtonLeft.UpInput())}
+	if got := backend.lastBatch(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("got release batch %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
+
+func TestSequenceTypeEncodesSurrogatePairs(t *testing.T) {
+	backend := &fakeBackend{}
+
+	// U+1F600 is encoded as the surrogate pair 0xD83D 0xDE00.
+	err := NewSequence().Type("a\U0001F600").Run(context.Background(), backend)
+	if err != nil {
+		t.Fatalf("run failed - %s", err)
+	}
+
+	exp := []Input{
+		unicodeInput('a', false),
+		unicodeInput('a', true),
+		unicodeInput(0xD83D, false),
+		unicodeInput(0xDE00, false),
+		unicodeInput(0xD83D, true),
+		unicodeInput(0xDE00, true),
+	}
+	if got := backend.inputs(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("got inputs %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
+
+func TestSequenceTypeMapsControlCharacters(t *testing.T) {
+	backend := &fakeBackend{}
+
+	err := NewSequence().Type("\r\n\t\b").Run(context.Background(), backend)
+	if err != nil {
+		t.Fatalf("run failed - %s", err)
+	}
+
+	// "\r\n" is typed as a single Return key press.
+	exp := []Input{
+		keyDown(VKReturn), keyUp(VKReturn),
+		keyDown(VKTab), keyUp(VKTab),
+		keyDown(VKBack), keyUp(VKBack),
+	}
+	if got := backend.inputs(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("got inputs %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
@@ -0,0 +1,336 @@
+package user32util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// NewInputSession creates a new InputSession that sends input using
+// the provided user32 DLL.
+func NewInputSession(user32 *User32DLL) *InputSession {
+	return NewBackendInputSession(NewUser32Backend(user32))
+}
+
+// NewBackendInputSession creates a new InputSession that sends input
+// using the provided Backend.
+func NewBackendInputSession(backend Backend) *InputSession {
+	return &InputSession{
+		backend: backend,
+	}
+}
+
+// InputSession sends input while recording which keys and mouse buttons
+// it has pressed, but not yet released. This makes it possible to release
+// held inputs if a program exits between sending a key down and a key up,
+// which would otherwise leave the user's desktop with a stuck modifier key
+// or a held mouse button.
+//
+// Inputs sent outside of the session (e.g., by calling SendKeydbInput
+// directly) are not recorded.
+//
+// InputSession implements the Backend interface. Sessions are safe
+// for concurrent use.
+type InputSession struct {
+	backend Backend
+	mu      sync.Mutex
+	tracker pressTracker
+	closed  bool
+}
+
+// SendKeydbInput sends a single KeybdInput and records its press state.
+//
+// Refer to SendKeydbInput for more information.
+func (o *InputSession) SendKeydbInput(input KeybdInput) error {
+	return o.SendInputs([]Input{NewKeybdInput(input)})
+}
+
+// SendMouseInput sends a single MouseInput and records the state of
+// any mouse buttons that it presses or releases.
+//
+// Refer to SendMouseInput for more information.
+func (o *InputSession) SendMouseInput(input MouseInput) error {
+	return o.SendInputs([]Input{NewMouseInput(input)})
+}
+
+// SendInputs sends several inputs and records the state of any keys and
+// mouse buttons that they press or release.
+//
+// If sending fails, the inputs are recorded anyway because some of them
+// may have been inserted into the input stream. Releasing a key that is
+// not pressed is harmless.
+//
+// Refer to SendInputs for more information.
+func (o *InputSession) SendInputs(inputs []Input) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return errSessionClosed
+	}
+
+	err := o.backend.SendInputs(inputs)
+
+	o.tracker.inputs(inputs)
+
+	return err
+}
+
+// SetCursorPos moves the mouse cursor using the session's Backend.
+func (o *InputSession) SetCursorPos(x int32, y int32) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return errSessionClosed
+	}
+
+	return o.backend.SetCursorPos(x, y)
+}
+
+// ReleaseAll releases every key and mouse button that the session has
+// pressed, but not yet released. Inputs are released in the reverse order
+// in which they were pressed.
+func (o *InputSession) ReleaseAll() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.releaseAll()
+}
+
+func (o *InputSession) releaseAll() error {
+	return o.tracker.releaseUsing(o.backend)
+}
+
+// Close releases all held inputs and prevents the session from sending
+// any additional input.
+func (o *InputSession) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+
+	o.closed = true
+
+	return o.releaseAll()
+}
+
+// ReleaseOnPanic releases all held inputs if the calling goroutine is
+// panicking. The panic is then resumed. It must be called using defer:
+//	session := user32util.NewInputSession(user32)
+//	defer session.ReleaseOnPanic()
+func (o *InputSession) ReleaseOnPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	o.Close()
+
+	panic(r)
+}
+
+// ReleaseOnSignal closes the session when one of the specified signals
+// is received, and then exits the process with a non-zero exit code.
+// If no signals are specified, os.Interrupt is used.
+//
+// The returned function stops the signal handling. It does not close
+// the session.
+func (o *InputSession) ReleaseOnSignal(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+
+	received := make(chan os.Signal, 1)
+	stopped := make(chan struct{})
+	signal.Notify(received, signals...)
+
+	go func() {
+		select {
+		case <-stopped:
+			return
+		case <-received:
+			o.Close()
+			os.Exit(1)
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(received)
+			close(stopped)
+		})
+	}
+}
+
+var errSessionClosed = errors.New("input session is closed")
+
+// pressTracker records which keys and mouse buttons are held down based
+// on the inputs that have been sent.
+//
+// It is not safe for concurrent use.
+type pressTracker struct {
+	keys    []KeybdInput
+	buttons []MouseInput
+}
+
+// inputs updates the tracker's state using several sent inputs.
+func (o *pressTracker) inputs(inputs []Input) {
+	for _, input := range inputs {
+		if kb, isKb := input.KeybdInput(); isKb {
+			o.keyboard(kb)
+		} else if mouse, isMouse := input.MouseInput(); isMouse {
+			o.mouse(mouse)
+		}
+	}
+}
+
+// keyboard updates the tracker's state using a sent KeybdInput.
+func (o *pressTracker) keyboard(input KeybdInput) {
+	for i := range o.keys {
+		if sameKey(o.keys[i], input) {
+			if input.DwFlags&KeyEventFKeyUp != 0 {
+				o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			}
+
+			return
+		}
+	}
+
+	if input.DwFlags&KeyEventFKeyUp == 0 {
+		o.keys = append(o.keys, input)
+	}
+}
+
+// mouse updates the tracker's state using a sent MouseInput.
+func (o *pressTracker) mouse(input MouseInput) {
+	for _, button := range mouseButtonFlags {
+		switch {
+		case button.xButton != 0:
+			if input.MouseData&button.xButton == 0 {
+				continue
+			}
+		case input.DwFlags&(button.down|button.up) == 0:
+			continue
+		}
+
+		if input.DwFlags&button.down != 0 && input.DwFlags&button.up == 0 {
+			o.addButton(button)
+		} else if input.DwFlags&button.up != 0 {
+			o.removeButton(button)
+		}
+	}
+}
+
+func (o *pressTracker) addButton(button mouseButtonFlag) {
+	for _, held := range o.buttons {
+		if button.matches(held) {
+			return
+		}
+	}
+
+	o.buttons = append(o.buttons, button.release())
+}
+
+func (o *pressTracker) removeButton(button mouseButtonFlag) {
+	for i, held := range o.buttons {
+		if button.matches(held) {
+			o.buttons = append(o.buttons[:i], o.buttons[i+1:]...)
+			return
+		}
+	}
+}
+
+// releases returns the inputs needed to release every held key and
+// mouse button. Keys are released first, in the reverse order in which
+// they were pressed, followed by mouse buttons.
+func (o *pressTracker) releases() []Input {
+	var inputs []Input
+
+	for i := len(o.keys) - 1; i >= 0; i-- {
+		key := o.keys[i]
+		key.DwFlags |= KeyEventFKeyUp
+		key.Time = 0
+		key.DwExtraInfo = 0
+		inputs = append(inputs, NewKeybdInput(key))
+	}
+
+	for i := len(o.buttons) - 1; i >= 0; i-- {
+		inputs = append(inputs, NewMouseInput(o.buttons[i]))
+	}
+
+	return inputs
+}
+
+// releaseUsing sends the inputs needed to release every held key and
+// mouse button using backend, and then resets the tracker.
+func (o *pressTracker) releaseUsing(backend Backend) error {
+	releases := o.releases()
+
+	o.reset()
+
+	if len(releases) == 0 {
+		return nil
+	}
+
+	err := backend.SendInputs(releases)
+	if err != nil {
+		return fmt.Errorf("failed to release held inputs - %s", err)
+	}
+
+	return nil
+}
+
+func (o *pressTracker) reset() {
+	o.keys = nil
+	o.buttons = nil
+}
+
+// sameKey returns true if a and b refer to the same physical key (or the
+// same character in the case of KeyEventFUnicode).
+func sameKey(a KeybdInput, b KeybdInput) bool {
+	const mode = KeyEventFUnicode | KeyEventFScanCode
+
+	if a.DwFlags&mode != b.DwFlags&mode {
+		return false
+	}
+
+	if a.DwFlags&mode != 0 {
+		return a.WScan == b.WScan &&
+			a.DwFlags&KeyEventFExtendedKey == b.DwFlags&KeyEventFExtendedKey
+	}
+
+	return a.WVK == b.WVK
+}
+
+// mouseButtonFlag maps a mouse button's down flag to its up flag.
+type mouseButtonFlag struct {
+	down    uint32
+	up      uint32
+	xButton uint32
+}
+
+func (o mouseButtonFlag) matches(release MouseInput) bool {
+	return release.DwFlags == o.up && release.MouseData == o.xButton
+}
+
+func (o mouseButtonFlag) release() MouseInput {
+	return MouseInput{
+		MouseData: o.xButton,
+		DwFlags:   o.up,
+	}
+}
+
+var mouseButtonFlags = []mouseButtonFlag{
+	{down: MouseEventFLeftDown, up: MouseEventFLeftUp},
+	{down: MouseEventFRightDown, up: MouseEventFRightUp},
+	{down: MouseEventFMiddleDown, up: MouseEventFMiddleUp},
+	{down: MouseEventFXDown, up: MouseEventFXUp, xButton: XButton1},
+	{down: MouseEventFXDown, up: MouseEventFXUp, xButton: XButton2},
+}
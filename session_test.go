@@ -0,0 +1,146 @@
+package user32util
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestInputSessionReleaseAllOrder(t *testing.T) {
+	backend := &fakeBackend{}
+	session := NewBackendInputSession(backend)
+
+	sent := []Input{
+		keyDown(VKLShift),
+		NewMouseInput(MouseButtonLeft.DownInput()),
+		keyDown(VKLControl),
+		keyDown(VKLMenu),
+		NewMouseInput(MouseButtonX2.DownInput()),
+		keyUp(VKLControl),
+		unicodeInput('a', false),
+	}
+
+	for _, input := range sent {
+		err := session.SendInputs([]Input{input})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := session.ReleaseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are released in reverse order, followed by mouse buttons in
+	// reverse order. The released control key is not released again.
+	exp := []Input{
+		unicodeInput('a', true),
+		keyUp(VKLMenu),
+		keyUp(VKLShift),
+		NewMouseInput(MouseButtonX2.UpInput()),
+		NewMouseInput(MouseButtonLeft.UpInput()),
+	}
+
+	got := backend.lastBatch()
+	if !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("released %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+
+	err = session.ReleaseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backend.batches) != len(sent)+1 {
+		t.Fatalf("expected a second ReleaseAll to send nothing, got %d batches", len(backend.batches))
+	}
+}
+
+func TestInputSessionTracksRepeatedPresses(t *testing.T) {
+	backend := &fakeBackend{}
+	session := NewBackendInputSession(backend)
+
+	err := session.SendInputs([]Input{
+		keyDown(VKLShift),
+		keyDown(VKLShift),
+		NewMouseInput(MouseButtonRight.DownInput()),
+		NewMouseInput(MouseButtonRight.DownInput()),
+		NewMouseInput(MouseButtonRight.UpInput()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.ReleaseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Input{keyUp(VKLShift)}
+	if got := backend.lastBatch(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("released %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
+
+func TestInputSessionCloseIsIdempotent(t *testing.T) {
+	backend := &fakeBackend{}
+	session := NewBackendInputSession(backend)
+
+	err := session.SendKeydbInput(KeyDownInput(VKLWin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = session.Close()
+	if err != nil {
+		t.Fatalf("second close failed - %s", err)
+	}
+
+	exp := [][]Input{{keyDown(VKLWin)}, {keyUp(VKLWin)}}
+	if !reflect.DeepEqual(batchValues(backend.batches), batchValues(exp)) {
+		t.Fatalf("got batches %+v, expected %+v", batchValues(backend.batches), batchValues(exp))
+	}
+
+	err = session.SendKeydbInput(KeyDownInput(VKLWin))
+	if !errors.Is(err, errSessionClosed) {
+		t.Fatalf("got error %v after close, expected %v", err, errSessionClosed)
+	}
+
+	err = session.SetCursorPos(1, 2)
+	if !errors.Is(err, errSessionClosed) {
+		t.Fatalf("got error %v after close, expected %v", err, errSessionClosed)
+	}
+
+	if len(backend.batches) != 2 || len(backend.moves) != 0 {
+		t.Fatal("expected no input to be sent after close")
+	}
+}
+
+func TestInputSessionRecordsInputsWhenSendFails(t *testing.T) {
+	sendErr := errors.New("send failed")
+	backend := &fakeBackend{sendErr: sendErr}
+	session := NewBackendInputSession(backend)
+
+	err := session.SendInputs([]Input{keyDown(VKRControl)})
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("got error %v, expected %v", err, sendErr)
+	}
+
+	backend.sendErr = nil
+
+	err = session.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []Input{keyUp(VKRControl)}
+	if got := backend.lastBatch(); !reflect.DeepEqual(inputValues(got), inputValues(exp)) {
+		t.Fatalf("released %+v, expected %+v", inputValues(got), inputValues(exp))
+	}
+}
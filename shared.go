@@ -0,0 +1,74 @@
+package user32util
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrHooksInstalled is returned when releasing a User32DLL while hooks
+// created using it are still installed. Release the listeners (or close
+// the Hub) that use the DLL first.
+var ErrHooksInstalled = errors.New("cannot release user32 dll while hooks are installed")
+
+var errNotAcquired = errors.New("user32 dll was released more times than it was acquired")
+
+var sharedUser32 struct {
+	mu   sync.Mutex
+	dll  *User32DLL
+	refs int
+}
+
+// Acquire returns the process-wide User32DLL, loading it if needed.
+// Each call to Acquire must be paired with a call to the DLL's Release
+// method. The DLL is released when its last reference is released.
+//
+// Acquire lets independent packages in a single program share one loaded
+// copy of user32. It is safe for concurrent use.
+func Acquire() (*User32DLL, error) {
+	return AcquireWithConfig(Config{})
+}
+
+// AcquireWithConfig is like Acquire, but uses the specified Config if
+// the DLL needs to be loaded. The Config is ignored if the DLL was
+// already loaded by an earlier call.
+func AcquireWithConfig(config Config) (*User32DLL, error) {
+	sharedUser32.mu.Lock()
+	defer sharedUser32.mu.Unlock()
+
+	if sharedUser32.dll == nil {
+		dll, err := LoadUser32DLLWithConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		dll.shared = true
+		sharedUser32.dll = dll
+	}
+
+	sharedUser32.refs++
+
+	return sharedUser32.dll, nil
+}
+
+// releaseShared releases one reference to the process-wide User32DLL.
+func releaseShared(dll *User32DLL) error {
+	sharedUser32.mu.Lock()
+	defer sharedUser32.mu.Unlock()
+
+	if sharedUser32.dll != dll || sharedUser32.refs == 0 {
+		return errNotAcquired
+	}
+
+	if sharedUser32.refs == 1 {
+		err := dll.release()
+		if err != nil {
+			return err
+		}
+
+		sharedUser32.dll = nil
+	}
+
+	sharedUser32.refs--
+
+	return nil
+}
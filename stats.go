@@ -0,0 +1,270 @@
+package user32util
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// CallbackDurationBuckets are the upper bounds of the callback duration
+// histogram buckets reported in ListenerStats.
+var CallbackDurationBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Slots in hookStats.received. Keyboard messages are in the 0x01xx
+// range and mouse messages are in the 0x02xx range.
+const (
+	keyboardMessageSlot = 0
+	mouseMessageSlot    = 16
+	otherMessageSlot    = 32
+	numMessageSlots     = 33
+)
+
+// ListenerStats is a snapshot of a listener's counters.
+type ListenerStats struct {
+	// Received is the number of events received, keyed by their
+	// message type (e.g., WMKeyDown or WMMouseMove).
+	Received map[uintptr]uint64
+
+	// Blocked is the number of events blocked by the listener.
+	Blocked uint64
+
+	// Callbacks is a histogram of the time spent in the listener's
+	// hook callbacks.
+	Callbacks DurationHistogram
+
+	// MaxCallback is the longest time spent in a single hook callback.
+	MaxCallback time.Duration
+
+	// HooksTimeout is the LowLevelHooksTimeout configured for the
+	// current user, or zero if it is not configured. Windows removes
+	// hooks whose callbacks take longer than this.
+	HooksTimeout time.Duration
+
+	// Queued is true if events are delivered through queues, in which
+	// case QueueDepth and Dropped are reported. Listeners deliver events
+	// synchronously on the hook's thread and do not have queues.
+	Queued bool
+
+	// QueueDepth is the number of events waiting to be read by
+	// subscribers. It is only reported by Hub.
+	QueueDepth int
+
+	// Dropped is the number of events dropped because a subscriber's
+	// buffer was full. It is only reported by Hub.
+	Dropped uint64
+}
+
+// DurationHistogram is a histogram of durations.
+type DurationHistogram struct {
+	// Bounds are the upper bounds of the buckets.
+	Bounds []time.Duration
+
+	// Counts are the number of observations in each bucket. Counts has
+	// one more element than Bounds; the last bucket counts observations
+	// that exceed the last bound.
+	Counts []uint64
+
+	// Count is the total number of observations.
+	Count uint64
+
+	// Sum is the sum of all observations.
+	Sum time.Duration
+}
+
+// hookStats holds the counters of a hook thread. The counters are written
+// by the hook's thread and may be read concurrently.
+type hookStats struct {
+	// The 64-bit fields are accessed atomically and must be first to
+	// guarantee alignment on 32-bit platforms.
+	blocked  uint64
+	count    uint64
+	sum      int64
+	max      int64
+	received [numMessageSlots]uint64
+	buckets  []uint64
+}
+
+func newHookStats() *hookStats {
+	return &hookStats{
+		buckets: make([]uint64, len(CallbackDurationBuckets)+1),
+	}
+}
+
+// observe records a single event. It is called on the hook's thread.
+func (o *hookStats) observe(wParam uintptr, blocked bool, elapsed time.Duration) {
+	atomic.AddUint64(&o.received[messageSlot(wParam)], 1)
+
+	if blocked {
+		atomic.AddUint64(&o.blocked, 1)
+	}
+
+	bucket := len(CallbackDurationBuckets)
+	for i, bound := range CallbackDurationBuckets {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	atomic.AddUint64(&o.buckets[bucket], 1)
+	atomic.AddUint64(&o.count, 1)
+	atomic.AddInt64(&o.sum, int64(elapsed))
+
+	for {
+		max := atomic.LoadInt64(&o.max)
+		if int64(elapsed) <= max || atomic.CompareAndSwapInt64(&o.max, max, int64(elapsed)) {
+			break
+		}
+	}
+}
+
+func (o *hookStats) snapshot() ListenerStats {
+	stats := ListenerStats{
+		Received: make(map[uintptr]uint64),
+		Blocked:  atomic.LoadUint64(&o.blocked),
+		Callbacks: DurationHistogram{
+			Bounds: append([]time.Duration(nil), CallbackDurationBuckets...),
+			Counts: make([]uint64, len(o.buckets)),
+			Count:  atomic.LoadUint64(&o.count),
+			Sum:    time.Duration(atomic.LoadInt64(&o.sum)),
+		},
+		MaxCallback:  time.Duration(atomic.LoadInt64(&o.max)),
+		HooksTimeout: cachedHooksTimeout(),
+	}
+
+	for i := range o.buckets {
+		stats.Callbacks.Counts[i] = atomic.LoadUint64(&o.buckets[i])
+	}
+
+	for i := range o.received {
+		count := atomic.LoadUint64(&o.received[i])
+		if count > 0 {
+			stats.Received[slotMessage(i)] = count
+		}
+	}
+
+	return stats
+}
+
+func messageSlot(wParam uintptr) int {
+	switch {
+	case wParam >= 0x0100 && wParam < 0x0110:
+		return keyboardMessageSlot + int(wParam-0x0100)
+	case wParam >= 0x0200 && wParam < 0x0210:
+		return mouseMessageSlot + int(wParam-0x0200)
+	default:
+		return otherMessageSlot
+	}
+}
+
+// slotMessage is the inverse of messageSlot. Messages in the other
+// slot are reported as zero.
+func slotMessage(slot int) uintptr {
+	switch {
+	case slot >= mouseMessageSlot && slot < otherMessageSlot:
+		return 0x0200 + uintptr(slot-mouseMessageSlot)
+	case slot >= keyboardMessageSlot && slot < mouseMessageSlot:
+		return 0x0100 + uintptr(slot-keyboardMessageSlot)
+	default:
+		return 0
+	}
+}
+
+// MessageName returns the name of a keyboard or mouse message (e.g.,
+// "WM_KEYDOWN") as used in the Windows API documentation. Unknown
+// messages are formatted as hexadecimal numbers.
+func MessageName(message uintptr) string {
+	switch message {
+	case uintptr(WMKeyDown):
+		return "WM_KEYDOWN"
+	case uintptr(WMKeyUp):
+		return "WM_KEYUP"
+	case uintptr(WHSystemKeyDown):
+		return "WM_SYSKEYDOWN"
+	case uintptr(WMSystemKeyUp):
+		return "WM_SYSKEYUP"
+	case uintptr(WMMouseMove):
+		return "WM_MOUSEMOVE"
+	case uintptr(WMLButtonDown):
+		return "WM_LBUTTONDOWN"
+	case uintptr(WMLButtonUp):
+		return "WM_LBUTTONUP"
+	case uintptr(WMRButtonDown):
+		return "WM_RBUTTONDOWN"
+	case uintptr(WMRButtonUp):
+		return "WM_RBUTTONUP"
+	case uintptr(WMMButtonDown):
+		return "WM_MBUTTONDOWN"
+	case uintptr(WMMButtonUp):
+		return "WM_MBUTTONUP"
+	case uintptr(WMMouseWheel):
+		return "WM_MOUSEWHEEL"
+	case uintptr(WMXButtonDown):
+		return "WM_XBUTTONDOWN"
+	case uintptr(WMXButtonUp):
+		return "WM_XBUTTONUP"
+	case uintptr(WMMouseHWheel):
+		return "WM_MOUSEHWHEEL"
+	default:
+		return fmt.Sprintf("0x%04X", message)
+	}
+}
+
+var hooksTimeout struct {
+	once  sync.Once
+	value time.Duration
+}
+
+// cachedHooksTimeout returns the LowLevelHooksTimeout, reading it from
+// the registry the first time it is called. Windows only applies changes
+// to the value after the user logs in again, so there is no need to read
+// it on every stats snapshot.
+func cachedHooksTimeout() time.Duration {
+	hooksTimeout.once.Do(func() {
+		hooksTimeout.value = LowLevelHooksTimeout()
+	})
+
+	return hooksTimeout.value
+}
+
+// LowLevelHooksTimeout returns the LowLevelHooksTimeout configured for
+// the current user, or zero if it is not configured.
+//
+// From the Windows API documentation:
+//	The hook procedure should process a message in less time than the
+//	data entry specified in the LowLevelHooksTimeout value in the
+//	following registry key:
+//	HKEY_CURRENT_USER\Control Panel\Desktop
+//	The value is in milliseconds. If the hook procedure times out, the
+//	system passes the message to the next hook.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/winmsg/lowlevelkeyboardproc
+func LowLevelHooksTimeout() time.Duration {
+	key, err := registry.OpenKey(registry.CURRENT_USER, `Control Panel\Desktop`, registry.QUERY_VALUE)
+	if err != nil {
+		return 0
+	}
+	defer key.Close()
+
+	ms, _, err := key.GetIntegerValue("LowLevelHooksTimeout")
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,81 @@
+package user32util
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const getTickCountName = "GetTickCount"
+
+var kernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+// GetTickCount returns the number of milliseconds that have elapsed since
+// the system was started. The value wraps around to zero approximately
+// every 49.7 days.
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/api/sysinfoapi/nf-sysinfoapi-gettickcount
+func GetTickCount() uint32 {
+	ret, _, _ := kernel32.NewProc(getTickCountName).Call()
+	return uint32(ret)
+}
+
+// TickDuration returns the amount of time between two tick counts (e.g.,
+// those found in KbdllHookStruct.Time and MsllHookStruct.Time). The result
+// is correct across a wraparound as long as the tick counts are less than
+// approximately 24.8 days apart. It is negative if to is before from.
+func TickDuration(from uint32, to uint32) time.Duration {
+	return time.Duration(int32(to-from)) * time.Millisecond
+}
+
+// NewTickClock creates a new TickClock anchored at the current tick
+// count and wall-clock time.
+func NewTickClock() *TickClock {
+	return NewTickClockAt(GetTickCount(), time.Now())
+}
+
+// NewTickClockAt creates a new TickClock anchored at the specified tick
+// count and time.
+func NewTickClockAt(tick uint32, at time.Time) *TickClock {
+	return &TickClock{
+		anchorTime: at,
+		anchorTick: int64(tick),
+		latestTick: int64(tick),
+	}
+}
+
+// TickClock converts tick counts into time.Time values using an anchoring
+// tick count and wall-clock time pair.
+//
+// Tick counts are 32-bit values that wrap around approximately every 49.7
+// days. TickClock extends them to 64 bits by tracking the most recent tick
+// count that it has converted. This allows it to run indefinitely, provided
+// that it converts a tick count at least once every 24.8 days.
+//
+// The times returned by a TickClock created by NewTickClock carry Go's
+// monotonic clock reading, meaning the difference between two times
+// (calculated using time.Time.Sub) is not affected by changes to the
+// wall clock.
+//
+// TickClock is safe for concurrent use.
+type TickClock struct {
+	mu         sync.Mutex
+	anchorTime time.Time
+	anchorTick int64
+	latestTick int64
+}
+
+// Time converts a tick count into a time.Time.
+func (o *TickClock) Time(tick uint32) time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	extended := o.latestTick + int64(int32(tick-uint32(o.latestTick)))
+	if extended > o.latestTick {
+		o.latestTick = extended
+	}
+
+	return o.anchorTime.Add(time.Duration(extended-o.anchorTick) * time.Millisecond)
+}
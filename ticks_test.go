@@ -0,0 +1,101 @@
+package user32util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTickDuration(t *testing.T) {
+	tests := []struct {
+		from uint32
+		to   uint32
+		exp  time.Duration
+	}{
+		{from: 1000, to: 1500, exp: 500 * time.Millisecond},
+		{from: 1500, to: 1000, exp: -500 * time.Millisecond},
+		{from: 0xFFFFFFFF, to: 0, exp: time.Millisecond},
+		{from: 0xFFFFFF00, to: 0x00000100, exp: 512 * time.Millisecond},
+		{from: 0x00000100, to: 0xFFFFFF00, exp: -512 * time.Millisecond},
+		{from: 0, to: 0x7FFFFFFF, exp: 0x7FFFFFFF * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if got := TickDuration(test.from, test.to); got != test.exp {
+			t.Fatalf("duration from %#x to %#x is %s, expected %s",
+				test.from, test.to, got, test.exp)
+		}
+	}
+}
+
+func TestTickClockWraparound(t *testing.T) {
+	anchor := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewTickClockAt(0xFFFFFF00, anchor)
+
+	tests := []struct {
+		tick uint32
+		exp  time.Duration
+	}{
+		{tick: 0xFFFFFF00, exp: 0},
+		{tick: 0xFFFFFFFE, exp: 254 * time.Millisecond},
+		{tick: 0xFFFFFFFF, exp: 255 * time.Millisecond},
+		{tick: 0x00000000, exp: 256 * time.Millisecond},
+		{tick: 0x00000001, exp: 257 * time.Millisecond},
+		{tick: 0x00000100, exp: 512 * time.Millisecond},
+		// Out of order ticks on either side of the wraparound.
+		{tick: 0xFFFFFFF0, exp: 240 * time.Millisecond},
+		{tick: 0x00000080, exp: 384 * time.Millisecond},
+		{tick: 0x00000200, exp: 768 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		got := clock.Time(test.tick).Sub(anchor)
+		if got != test.exp {
+			t.Fatalf("tick %#x is %s after the anchor, expected %s", test.tick, got, test.exp)
+		}
+	}
+}
+
+func TestTickClockOutOfOrder(t *testing.T) {
+	anchor := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewTickClockAt(5000, anchor)
+
+	ticks := []uint32{6000, 5500, 7000, 4000, 6500}
+	exp := []time.Duration{
+		time.Second,
+		500 * time.Millisecond,
+		2 * time.Second,
+		-time.Second,
+		1500 * time.Millisecond,
+	}
+
+	for i, tick := range ticks {
+		got := clock.Time(tick).Sub(anchor)
+		if got != exp[i] {
+			t.Fatalf("tick %d is %s after the anchor, expected %s", tick, got, exp[i])
+		}
+	}
+
+	// An earlier tick must not move the clock backwards: later ticks
+	// are still extended relative to the latest tick.
+	if got := clock.Time(7001).Sub(anchor); got != 2001*time.Millisecond {
+		t.Fatalf("tick 7001 is %s after the anchor, expected 2.001s", got)
+	}
+}
+
+func TestTickClockMultipleWraparounds(t *testing.T) {
+	anchor := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewTickClockAt(0, anchor)
+
+	const step = 1 << 30
+
+	var tick uint32
+	for i := 1; i <= 12; i++ {
+		tick += step
+
+		got := clock.Time(tick).Sub(anchor)
+		exp := time.Duration(i) * step * time.Millisecond
+		if got != exp {
+			t.Fatalf("step %d: tick %#x is %s after the anchor, expected %s", i, tick, got, exp)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package user32util
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default TypingModel values.
+const (
+	DefaultTypingWPM       = 60
+	DefaultTypingHoldMean  = 90 * time.Millisecond
+	DefaultTypingTypoPause = 300 * time.Millisecond
+)
+
+// minKeyDelay is the shortest delay generated between two key events.
+const minKeyDelay = 10 * time.Millisecond
+
+// KeyStroke is a single scheduled keyboard input.
+type KeyStroke struct {
+	// At is the time at which the input is sent, relative to
+	// the start of typing.
+	At time.Duration
+
+	// Input is the keyboard input.
+	Input KeybdInput
+}
+
+// TypingModel schedules key presses and releases for a string so that
+// they resemble a human typing on a keyboard.
+//
+// The zero value types at DefaultTypingWPM with fixed delays. Scheduling
+// is deterministic: the same model and text always produce the same
+// key strokes.
+type TypingModel struct {
+	// WPM is the target typing speed in words per minute, where a word
+	// is five characters. Zero is replaced with DefaultTypingWPM.
+	WPM float64
+
+	// Variability is the standard deviation of the delay between key
+	// presses, as a fraction of the mean delay (e.g., 0.3). Zero
+	// disables variation.
+	Variability float64
+
+	// BigramDelays overrides the mean delay between pressing the first
+	// and second character of specific two-character strings (e.g.,
+	// "th"). Bigrams are case-sensitive.
+	BigramDelays map[string]time.Duration
+
+	// HoldMean and HoldStdDev describe the normal distribution of the
+	// amount of time each key is held down. A zero HoldMean is replaced
+	// with DefaultTypingHoldMean.
+	HoldMean   time.Duration
+	HoldStdDev time.Duration
+
+	// TypoRate is the probability (0 through 1) that a character is
+	// preceded by a typo: a neighbouring key on a QWERTY keyboard that is
+	// typed, noticed, and deleted using backspace. Zero disables typos.
+	TypoRate float64
+
+	// TypoPause is the amount of time it takes to notice a typo.
+	// Zero is replaced with DefaultTypingTypoPause.
+	TypoPause time.Duration
+
+	// Seed seeds the random values used by the model.
+	Seed int64
+}
+
+// Schedule returns the key strokes needed to type text, sorted by time.
+// Characters are sent as KeyEventFUnicode inputs, with the exception of
+// line breaks, tabs and backspaces.
+func (o TypingModel) Schedule(text string) []KeyStroke {
+	s := typingScheduler{
+		model:  o,
+		rng:    rand.New(rand.NewSource(o.Seed)),
+		lastUp: make(map[rune]time.Duration),
+	}
+
+	var previous rune
+
+	for _, r := range text {
+		if r == '\n' && previous == '\r' {
+			previous = r
+			continue
+		}
+
+		if o.TypoRate > 0 && s.rng.Float64() < o.TypoRate {
+			typo, ok := qwertyNeighbour(r, s.rng)
+			if ok {
+				s.advance(previous, typo)
+				s.strike(typo)
+
+				pause := o.TypoPause
+				if pause == 0 {
+					pause = DefaultTypingTypoPause
+				}
+
+				s.now += pause
+				s.strike('\b')
+				previous = '\b'
+			}
+		}
+
+		s.advance(previous, r)
+		s.strike(r)
+		previous = r
+	}
+
+	sort.SliceStable(s.strokes, func(i int, j int) bool {
+		return s.strokes[i].At < s.strokes[j].At
+	})
+
+	return s.strokes
+}
+
+type typingScheduler struct {
+	model   TypingModel
+	rng     *rand.Rand
+	now     time.Duration
+	started bool
+	lastUp  map[rune]time.Duration
+	strokes []KeyStroke
+}
+
+// advance moves the scheduler's clock forward by the delay between
+// pressing previous and pressing next.
+func (o *typingScheduler) advance(previous rune, next rune) {
+	if !o.started {
+		o.started = true
+		return
+	}
+
+	wpm := o.model.WPM
+	if wpm <= 0 {
+		wpm = DefaultTypingWPM
+	}
+
+	mean := time.Duration(float64(time.Minute) / (wpm * 5))
+	if bigramDelay, ok := o.model.BigramDelays[string([]rune{previous, next})]; ok {
+		mean = bigramDelay
+	}
+
+	o.now += o.normal(mean, time.Duration(float64(mean)*o.model.Variability))
+}
+
+// strike schedules a key press at the current time, followed by its
+// release after a randomly generated hold duration.
+func (o *typingScheduler) strike(r rune) {
+	// Pressing a key that has not been released yet would generate
+	// an auto-repeat rather than a second key press.
+	if up, ok := o.lastUp[r]; ok && o.now <= up {
+		o.now = up + minKeyDelay
+	}
+
+	holdMean := o.model.HoldMean
+	if holdMean == 0 {
+		holdMean = DefaultTypingHoldMean
+	}
+
+	up := o.now + o.normal(holdMean, o.model.HoldStdDev)
+	o.lastUp[r] = up
+
+	for _, input := range charInputs(r, false) {
+		o.strokes = append(o.strokes, KeyStroke{At: o.now, Input: input})
+	}
+
+	for _, input := range charInputs(r, true) {
+		o.strokes = append(o.strokes, KeyStroke{At: up, Input: input})
+	}
+}
+
+// normal returns a normally distributed duration that is no shorter
+// than minKeyDelay.
+func (o *typingScheduler) normal(mean time.Duration, stdDev time.Duration) time.Duration {
+	d := mean + time.Duration(o.rng.NormFloat64()*float64(stdDev))
+	if d < minKeyDelay {
+		return minKeyDelay
+	}
+
+	return d
+}
+
+var qwertyRows = []string{
+	"1234567890",
+	"qwertyuiop",
+	"asdfghjkl",
+	"zxcvbnm",
+}
+
+// qwertyNeighbour returns a key next to r on the same row of a QWERTY
+// keyboard. The case of r is preserved.
+func qwertyNeighbour(r rune, rng *rand.Rand) (rune, bool) {
+	lower := unicode.ToLower(r)
+
+	for _, row := range qwertyRows {
+		i := strings.IndexRune(row, lower)
+		if i < 0 {
+			continue
+		}
+
+		var candidates []rune
+		if i > 0 {
+			candidates = append(candidates, rune(row[i-1]))
+		}
+		if i < len(row)-1 {
+			candidates = append(candidates, rune(row[i+1]))
+		}
+
+		neighbour := candidates[rng.Intn(len(candidates))]
+		if unicode.IsUpper(r) {
+			neighbour = unicode.ToUpper(neighbour)
+		}
+
+		return neighbour, true
+	}
+
+	return 0, false
+}
+
+// RunKeyStrokes sends scheduled key strokes using the provided Backend.
+//
+// If ctx is cancelled or an error occurs before all of the key strokes
+// are sent, keys that were pressed, but not yet released, are released
+// before RunKeyStrokes returns.
+func RunKeyStrokes(ctx context.Context, strokes []KeyStroke, backend Backend) error {
+	var tracker pressTracker
+
+	start := time.Now()
+
+	for _, stroke := range strokes {
+		err := sleepContext(ctx, stroke.At-time.Since(start))
+		if err == nil {
+			inputs := []Input{NewKeybdInput(stroke.Input)}
+			err = backend.SendInputs(inputs)
+			tracker.inputs(inputs)
+		}
+		if err != nil {
+			tracker.releaseUsing(backend)
+			return err
+		}
+	}
+
+	return nil
+}
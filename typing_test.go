@@ -0,0 +1,172 @@
+package user32util
+
+import (
+	"testing"
+	"time"
+)
+
+var typingTestModel = TypingModel{
+	WPM:         80,
+	Variability: 0.4,
+	BigramDelays: map[string]time.Duration{
+		"th": 40 * time.Millisecond,
+	},
+	HoldMean:   80 * time.Millisecond,
+	HoldStdDev: 30 * time.Millisecond,
+	TypoRate:   0.2,
+	Seed:       42,
+}
+
+const typingTestText = "the quick brown fox jumps over the lazy dog\r\nThe End"
+
+func TestTypingModelIsDeterministic(t *testing.T) {
+	first := typingTestModel.Schedule(typingTestText)
+	second := typingTestModel.Schedule(typingTestText)
+
+	if len(first) == 0 {
+		t.Fatal("no key strokes were scheduled")
+	}
+
+	if len(first) != len(second) {
+		t.Fatalf("got %d and %d key strokes for the same seed", len(first), len(second))
+	}
+
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("key stroke %d is %+v, then %+v", i, first[i], second[i])
+		}
+	}
+
+	reseeded := typingTestModel
+	reseeded.Seed++
+
+	other := reseeded.Schedule(typingTestText)
+	if len(other) == len(first) {
+		same := true
+		for i := range first {
+			if first[i] != other[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			t.Fatal("a different seed produced the same key strokes")
+		}
+	}
+}
+
+func TestTypingModelFixedDelays(t *testing.T) {
+	model := TypingModel{
+		WPM: 60,
+		BigramDelays: map[string]time.Duration{
+			"bc": 50 * time.Millisecond,
+		},
+	}
+
+	strokes := model.Schedule("abc")
+
+	// 60 WPM is 300 characters per minute.
+	mean := time.Minute / 300
+
+	exp := []KeyStroke{
+		{At: 0, Input: KeybdInput{WScan: 'a', DwFlags: KeyEventFUnicode}},
+		{At: DefaultTypingHoldMean, Input: KeybdInput{WScan: 'a', DwFlags: KeyEventFUnicode | KeyEventFKeyUp}},
+		{At: mean, Input: KeybdInput{WScan: 'b', DwFlags: KeyEventFUnicode}},
+		{At: mean + 50*time.Millisecond, Input: KeybdInput{WScan: 'c', DwFlags: KeyEventFUnicode}},
+		{At: mean + DefaultTypingHoldMean, Input: KeybdInput{WScan: 'b', DwFlags: KeyEventFUnicode | KeyEventFKeyUp}},
+		{At: mean + 50*time.Millisecond + DefaultTypingHoldMean, Input: KeybdInput{WScan: 'c', DwFlags: KeyEventFUnicode | KeyEventFKeyUp}},
+	}
+
+	if len(strokes) != len(exp) {
+		t.Fatalf("got %d key strokes, expected %d - %+v", len(strokes), len(exp), strokes)
+	}
+
+	for i := range exp {
+		if strokes[i] != exp[i] {
+			t.Fatalf("key stroke %d is %+v, expected %+v", i, strokes[i], exp[i])
+		}
+	}
+}
+
+func TestTypingModelDelayBounds(t *testing.T) {
+	models := []TypingModel{
+		typingTestModel,
+		{WPM: 2000, Variability: 2, HoldMean: time.Millisecond, HoldStdDev: time.Second, Seed: 7},
+		{WPM: 30, Variability: 0.5, TypoRate: 1, TypoPause: 100 * time.Millisecond, Seed: 99},
+	}
+
+	for i, model := range models {
+		strokes := model.Schedule("aaa bbb " + typingTestText)
+
+		var lastDown time.Duration
+		seenDown := false
+		downAt := make(map[KeybdInput]time.Duration)
+
+		for j, stroke := range strokes {
+			if j > 0 && stroke.At < strokes[j-1].At {
+				t.Fatalf("model %d: key stroke %d at %s is before key stroke %d at %s",
+					i, j, stroke.At, j-1, strokes[j-1].At)
+			}
+
+			key := stroke.Input
+			key.DwFlags &^= KeyEventFKeyUp
+
+			if stroke.Input.DwFlags&KeyEventFKeyUp == 0 {
+				if seenDown && stroke.At-lastDown < minKeyDelay {
+					t.Fatalf("model %d: key stroke %d is %s after the previous press",
+						i, j, stroke.At-lastDown)
+				}
+
+				if _, pressed := downAt[key]; pressed {
+					t.Fatalf("model %d: key stroke %d presses %+v before it was released",
+						i, j, key)
+				}
+
+				lastDown = stroke.At
+				seenDown = true
+				downAt[key] = stroke.At
+				continue
+			}
+
+			at, pressed := downAt[key]
+			if !pressed {
+				t.Fatalf("model %d: key stroke %d releases %+v before it was pressed",
+					i, j, key)
+			}
+
+			if stroke.At-at < minKeyDelay {
+				t.Fatalf("model %d: key stroke %d holds %+v for only %s",
+					i, j, key, stroke.At-at)
+			}
+
+			delete(downAt, key)
+		}
+
+		if len(downAt) > 0 {
+			t.Fatalf("model %d: keys were never released - %+v", i, downAt)
+		}
+	}
+}
+
+func TestTypingModelTyposAreDeleted(t *testing.T) {
+	model := TypingModel{TypoRate: 1, Seed: 3}
+
+	strokes := model.Schedule("sad")
+
+	var presses []KeybdInput
+	for _, stroke := range strokes {
+		if stroke.Input.DwFlags&KeyEventFKeyUp == 0 {
+			presses = append(presses, stroke.Input)
+		}
+	}
+
+	if len(presses) != 9 {
+		t.Fatalf("got %d key presses, expected 9 - %+v", len(presses), presses)
+	}
+
+	for i := 0; i < len(presses); i += 3 {
+		if presses[i+1] != KeyDownInput(VKBack) {
+			t.Fatalf("key press %d is %+v, expected a backspace", i+1, presses[i+1])
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package user32util
+
+// VirtualKey is a Windows virtual-key code.
+//
+// The codes for the keys 0 through 9 and A through Z are the same as
+// their ASCII values (e.g., VirtualKey('A')).
+//
+// Refer to the following Windows API document for more information:
+// https://docs.microsoft.com/en-us/windows/win32/inputdev/virtual-key-codes
+type VirtualKey uint16
+
+// Various virtual-key codes.
+const (
+	VKLButton  VirtualKey = 0x01
+	VKRButton  VirtualKey = 0x02
+	VKCancel   VirtualKey = 0x03
+	VKMButton  VirtualKey = 0x04
+	VKXButton1 VirtualKey = 0x05
+	VKXButton2 VirtualKey = 0x06
+	VKBack     VirtualKey = 0x08
+	VKTab      VirtualKey = 0x09
+	VKReturn   VirtualKey = 0x0D
+	VKShift    VirtualKey = 0x10
+	VKControl  VirtualKey = 0x11
+	VKMenu     VirtualKey = 0x12
+	VKPause    VirtualKey = 0x13
+	VKCapital  VirtualKey = 0x14
+	VKKana     VirtualKey = 0x15
+	VKEscape   VirtualKey = 0x1B
+	VKSpace    VirtualKey = 0x20
+	VKPrior    VirtualKey = 0x21
+	VKNext     VirtualKey = 0x22
+	VKEnd      VirtualKey = 0x23
+	VKHome     VirtualKey = 0x24
+	VKLeft     VirtualKey = 0x25
+	VKUp       VirtualKey = 0x26
+	VKRight    VirtualKey = 0x27
+	VKDown     VirtualKey = 0x28
+	VKSnapshot VirtualKey = 0x2C
+	VKInsert   VirtualKey = 0x2D
+	VKDelete   VirtualKey = 0x2E
+	VKLWin     VirtualKey = 0x5B
+	VKRWin     VirtualKey = 0x5C
+	VKApps     VirtualKey = 0x5D
+	VKDivide   VirtualKey = 0x6F
+	VKF1       VirtualKey = 0x70
+	VKF2       VirtualKey = 0x71
+	VKF3       VirtualKey = 0x72
+	VKF4       VirtualKey = 0x73
+	VKF5       VirtualKey = 0x74
+	VKF6       VirtualKey = 0x75
+	VKF7       VirtualKey = 0x76
+	VKF8       VirtualKey = 0x77
+	VKF9       VirtualKey = 0x78
+	VKF10      VirtualKey = 0x79
+	VKF11      VirtualKey = 0x7A
+	VKF12      VirtualKey = 0x7B
+	VKNumLock  VirtualKey = 0x90
+	VKScroll   VirtualKey = 0x91
+	VKLShift   VirtualKey = 0xA0
+	VKRShift   VirtualKey = 0xA1
+	VKLControl VirtualKey = 0xA2
+	VKRControl VirtualKey = 0xA3
+	VKLMenu    VirtualKey = 0xA4
+	VKRMenu    VirtualKey = 0xA5
+)
+
+// IsExtended returns true if the key must be sent with the
+// KeyEventFExtendedKey flag (e.g., the arrow keys).
+func (o VirtualKey) IsExtended() bool {
+	switch o {
+	case VKCancel, VKPrior, VKNext, VKEnd, VKHome, VKLeft, VKUp, VKRight,
+		VKDown, VKSnapshot, VKInsert, VKDelete, VKLWin, VKRWin, VKApps,
+		VKDivide, VKNumLock, VKRControl, VKRMenu:
+		return true
+	}
+
+	return false
+}
+
+// KeyDownInput returns a KeybdInput that presses the specified key.
+func KeyDownInput(vk VirtualKey) KeybdInput {
+	input := KeybdInput{
+		WVK: uint16(vk),
+	}
+
+	if vk.IsExtended() {
+		input.DwFlags |= KeyEventFExtendedKey
+	}
+
+	return input
+}
+
+// KeyUpInput returns a KeybdInput that releases the specified key.
+func KeyUpInput(vk VirtualKey) KeybdInput {
+	input := KeyDownInput(vk)
+	input.DwFlags |= KeyEventFKeyUp
+	return input
+}
+
+// charInputs returns the KeybdInput values needed to press (or release)
+// the key that produces r. Line breaks and tabs are mapped to their
+// respective keys. All other characters are sent as KeyEventFUnicode
+// inputs, which makes them independent of the keyboard layout.
+func charInputs(r rune, up bool) []KeybdInput {
+	var vk VirtualKey
+
+	switch r {
+	case '\n', '\r':
+		vk = VKReturn
+	case '\t':
+		vk = VKTab
+	case '\b':
+		vk = VKBack
+	}
+
+	if vk != 0 {
+		if up {
+			return []KeybdInput{KeyUpInput(vk)}
+		}
+		return []KeybdInput{KeyDownInput(vk)}
+	}
+
+	var flags uint32 = KeyEventFUnicode
+	if up {
+		flags |= KeyEventFKeyUp
+	}
+
+	var units []uint16
+	if r >= 0x10000 {
+		r -= 0x10000
+		units = []uint16{uint16(0xD800 + (r>>10)&0x3FF), uint16(0xDC00 + r&0x3FF)}
+	} else {
+		units = []uint16{uint16(r)}
+	}
+
+	inputs := make([]KeybdInput, len(units))
+	for i, unit := range units {
+		inputs[i] = KeybdInput{
+			WScan:   unit,
+			DwFlags: flags,
+		}
+	}
+
+	return inputs
+}
@@ -0,0 +1,392 @@
+package user32util
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default WatchdogConfig values.
+const (
+	DefaultWatchdogProbeInterval = 5 * time.Second
+	DefaultWatchdogProbeTimeout  = time.Second
+	DefaultWatchdogSlowCallback  = 200 * time.Millisecond
+	DefaultWatchdogBufferSize    = 16
+)
+
+// watchdogProbeKey is the key used by keyboard probes. It is an
+// unassigned virtual-key code, so other hooks that see the probe ignore
+// it. Hooks are called in the reverse order of installation, meaning
+// hooks installed after the listener's see the probe before the
+// listener blocks it.
+const watchdogProbeKey VirtualKey = 0xE8
+
+// watchdogProbeBase is the base value of the DwExtraInfo tokens used
+// by watchdog probes. Each watchdog uses a unique token so that a probe
+// is only consumed by the hook thread that sent it.
+const watchdogProbeBase uintptr = 0x77640000
+
+var watchdogProbeCount uint32
+
+// WatchdogEventKind describes an incident reported by a watchdog.
+type WatchdogEventKind int
+
+const (
+	// WatchdogSlowCallback is reported when a hook callback takes longer
+	// than WatchdogConfig.SlowCallback. Windows silently removes hooks
+	// whose callbacks exceed the LowLevelHooksTimeout.
+	WatchdogSlowCallback WatchdogEventKind = iota
+
+	// WatchdogProbeTimeout is reported when a probe does not reach its
+	// hook within WatchdogConfig.ProbeTimeout. The watchdog then attempts
+	// to reinstall the hook.
+	WatchdogProbeTimeout
+
+	// WatchdogProbeFailed is reported when a probe cannot be sent.
+	// The hook is not reinstalled in this case.
+	WatchdogProbeFailed
+
+	// WatchdogHookReinstalled is reported when a hook was reinstalled.
+	WatchdogHookReinstalled
+
+	// WatchdogReinstallFailed is reported when a hook could not be
+	// reinstalled.
+	WatchdogReinstallFailed
+)
+
+func (o WatchdogEventKind) String() string {
+	switch o {
+	case WatchdogSlowCallback:
+		return "slow_callback"
+	case WatchdogProbeTimeout:
+		return "probe_timeout"
+	case WatchdogProbeFailed:
+		return "probe_failed"
+	case WatchdogHookReinstalled:
+		return "hook_reinstalled"
+	case WatchdogReinstallFailed:
+		return "reinstall_failed"
+	default:
+		return "unknown"
+	}
+}
+
+// WatchdogEvent describes an incident reported by a watchdog.
+type WatchdogEvent struct {
+	Kind WatchdogEventKind
+
+	// Time is the time at which the incident was observed.
+	Time time.Time
+
+	// Hook is the kind of hook that the incident relates to.
+	// It is either KeyboardEvents or MouseEvents.
+	Hook EventKinds
+
+	// Latency is the callback's duration for WatchdogSlowCallback
+	// incidents, and the time waited for WatchdogProbeTimeout incidents.
+	Latency time.Duration
+
+	// Err is the error that caused a WatchdogProbeFailed or
+	// WatchdogReinstallFailed incident.
+	Err error
+}
+
+// WatchdogConfig configures a hook health watchdog.
+//
+// Refer to WithWatchdog for more information.
+type WatchdogConfig struct {
+	// ProbeInterval is the time between liveness probes.
+	// DefaultWatchdogProbeInterval is used if it is zero.
+	ProbeInterval time.Duration
+
+	// ProbeTimeout is how long to wait for a probe to reach its hook
+	// before the hook is considered to be removed.
+	// DefaultWatchdogProbeTimeout is used if it is zero.
+	ProbeTimeout time.Duration
+
+	// SlowCallback is the callback duration after which a
+	// WatchdogSlowCallback incident is reported.
+	// DefaultWatchdogSlowCallback is used if it is zero.
+	SlowCallback time.Duration
+
+	// BufferSize is the capacity of the events channel. Incidents are
+	// dropped if the channel is full. DefaultWatchdogBufferSize is used
+	// if it is zero.
+	BufferSize int
+
+	// InjectProbes enables liveness probes. If false, the watchdog only
+	// reports slow callbacks.
+	//
+	// Probes are injected using SendInput, which resets the system's
+	// idle timer like any other input. While probes are enabled, the
+	// screensaver, automatic workstation lock and sleep are never
+	// triggered by inactivity. Only enable probes if this is acceptable
+	// (e.g., on a kiosk or an automation machine).
+	InjectProbes bool
+
+	// Backend sends the probes. The listener's User32DLL is used
+	// if it is nil.
+	Backend Backend
+}
+
+// WithWatchdog configures a listener to monitor the health of its hooks.
+//
+// Windows silently removes a low-level hook if its callback takes longer
+// than the LowLevelHooksTimeout. The watchdog measures the duration of
+// each callback, reporting callbacks that are at risk of exceeding it.
+//
+// If WatchdogConfig.InjectProbes is true, the watchdog also periodically
+// injects a tagged no-op event (a key up of an unassigned key, or
+// a zero-distance mouse move) to check that the event reaches the hook.
+// If a probe does not arrive, the hook is removed and installed again
+// using SetWindowsHookExW on the hook's thread. Probes are blocked by
+// the hook and never reach the listener's callback.
+//
+// WARNING: injected probes count as user input. They reset the system's
+// idle timer, which prevents the screensaver, automatic workstation lock
+// and sleep from ever triggering while the listener is running. Probes
+// are therefore disabled by default.
+//
+// Incidents are reported on the listener's WatchdogEvents channel.
+//
+// Note that Windows does not deliver injected input to hooks while the
+// workstation is locked or a secure desktop is active. Probes may time
+// out, and hooks may be reinstalled, in these situations.
+func WithWatchdog(config WatchdogConfig) ListenerOption {
+	return func(c *listenerConfig) {
+		c.watchdog = &config
+	}
+}
+
+// watchdog monitors the hooks installed by a hookThread.
+type watchdog struct {
+	thread  *hookThread
+	config  WatchdogConfig
+	token   uintptr
+	arrived []chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+	events  chan WatchdogEvent
+	mu      sync.Mutex
+	closed  bool
+}
+
+func newWatchdog(thread *hookThread, config WatchdogConfig) *watchdog {
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = DefaultWatchdogProbeInterval
+	}
+
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = DefaultWatchdogProbeTimeout
+	}
+
+	if config.SlowCallback <= 0 {
+		config.SlowCallback = DefaultWatchdogSlowCallback
+	}
+
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultWatchdogBufferSize
+	}
+
+	if config.Backend == nil {
+		config.Backend = NewUser32Backend(thread.user32)
+	}
+
+	arrived := make([]chan struct{}, len(thread.specs))
+	for i := range arrived {
+		arrived[i] = make(chan struct{}, 1)
+	}
+
+	return &watchdog{
+		thread:  thread,
+		config:  config,
+		token:   watchdogProbeBase + uintptr(atomic.AddUint32(&watchdogProbeCount, 1)&0xFFFF),
+		arrived: arrived,
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		events:  make(chan WatchdogEvent, config.BufferSize),
+	}
+}
+
+func (o *watchdog) start() {
+	if !o.config.InjectProbes {
+		close(o.stopped)
+		return
+	}
+
+	go o.loop()
+}
+
+func (o *watchdog) loop() {
+	defer close(o.stopped)
+
+	ticker := time.NewTicker(o.config.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.quit:
+			return
+		case <-ticker.C:
+		}
+
+		for i := range o.thread.specs {
+			if !o.probe(i) {
+				return
+			}
+		}
+	}
+}
+
+// probe checks that the ith hook receives input, reinstalling it if it
+// does not. It returns false if the watchdog was stopped.
+func (o *watchdog) probe(i int) bool {
+	hook := hookEventKind(o.thread.specs[i].hookID)
+
+	// Discard a probe that arrived after a previous timeout.
+	select {
+	case <-o.arrived[i]:
+	default:
+	}
+
+	var input Input
+	switch hook {
+	case KeyboardEvents:
+		kb := KeyUpInput(watchdogProbeKey)
+		kb.DwExtraInfo = o.token
+		input = NewKeybdInput(kb)
+	case MouseEvents:
+		input = NewMouseInput(MouseInput{
+			DwFlags:     MouseEventFMove,
+			DwExtraInfo: o.token,
+		})
+	default:
+		return true
+	}
+
+	start := time.Now()
+
+	err := o.config.Backend.SendInputs([]Input{input})
+	if err != nil {
+		o.emit(WatchdogEvent{Kind: WatchdogProbeFailed, Hook: hook, Err: err})
+		return true
+	}
+
+	timer := time.NewTimer(o.config.ProbeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-o.quit:
+		return false
+	case <-o.arrived[i]:
+		return true
+	case <-timer.C:
+	}
+
+	o.emit(WatchdogEvent{Kind: WatchdogProbeTimeout, Hook: hook, Latency: time.Since(start)})
+
+	err = o.thread.requestReinstall(i)
+	if err != nil {
+		o.emit(WatchdogEvent{Kind: WatchdogReinstallFailed, Hook: hook, Err: err})
+	}
+
+	return true
+}
+
+// isProbe returns true if the hook event described by lParam is one of
+// this watchdog's probes. It is called on the hook's thread.
+func (o *watchdog) isProbe(i int, lParam uintptr) bool {
+	var extraInfo uintptr
+	switch o.thread.specs[i].hookID {
+	case whKeyboardLl:
+		extraInfo = kbdllHookStruct(lParam).DwExtraInfo
+	case whMouseLl:
+		extraInfo = msllHookStruct(lParam).DwExtraInfo
+	default:
+		return false
+	}
+
+	if extraInfo != o.token {
+		return false
+	}
+
+	select {
+	case o.arrived[i] <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+// observeCallback records the duration of a hook callback.
+// It is called on the hook's thread.
+func (o *watchdog) observeCallback(i int, d time.Duration) {
+	if d > o.config.SlowCallback {
+		o.emit(WatchdogEvent{
+			Kind:    WatchdogSlowCallback,
+			Hook:    hookEventKind(o.thread.specs[i].hookID),
+			Latency: d,
+		})
+	}
+}
+
+// reinstalled reports the result of reinstalling the ith hook.
+// It is called on the hook's thread.
+func (o *watchdog) reinstalled(i int, err error) {
+	event := WatchdogEvent{
+		Kind: WatchdogHookReinstalled,
+		Hook: hookEventKind(o.thread.specs[i].hookID),
+	}
+
+	if err != nil {
+		event.Kind = WatchdogReinstallFailed
+		event.Err = err
+	}
+
+	o.emit(event)
+}
+
+func (o *watchdog) emit(event WatchdogEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return
+	}
+
+	select {
+	case o.events <- event:
+	default:
+	}
+}
+
+// stop stops the probe goroutine and closes the events channel.
+func (o *watchdog) stop() {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return
+	}
+	o.closed = true
+	close(o.events)
+	o.mu.Unlock()
+
+	close(o.quit)
+	<-o.stopped
+}
+
+// hookEventKind returns the EventKinds reported by a hook.
+func hookEventKind(hookID int) EventKinds {
+	switch hookID {
+	case whKeyboardLl:
+		return KeyboardEvents
+	case whMouseLl:
+		return MouseEvents
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,235 @@
+package user32util
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// newUnavailableUser32 returns a User32DLL whose procedures are all
+// unavailable.
+func newUnavailableUser32() *User32DLL {
+	logger := Config{}.logger()
+	user32 := &windows.DLL{Name: user32DllName}
+
+	dll := &User32DLL{
+		user32: user32,
+		logger: logger,
+	}
+
+	for i, spec := range user32Procs {
+		dll.procs[i] = &lazyProc{
+			dll:    user32,
+			spec:   spec,
+			logger: logger,
+		}
+	}
+
+	return dll
+}
+
+// newTestWatchdog returns a watchdog for a keyboard hook and a mouse
+// hook that sends probes using backend.
+func newTestWatchdog(backend Backend) *watchdog {
+	thread := &hookThread{
+		user32: newUnavailableUser32(),
+		specs: []hookSpec{
+			{hookID: whKeyboardLl},
+			{hookID: whMouseLl},
+		},
+	}
+
+	return newWatchdog(thread, WatchdogConfig{
+		ProbeTimeout: 20 * time.Millisecond,
+		InjectProbes: true,
+		Backend:      backend,
+	})
+}
+
+// deliverProbe calls the watchdog's isProbe as the ith hook would for
+// an event with the specified DwExtraInfo.
+func deliverProbe(w *watchdog, i int, extraInfo uintptr) bool {
+	switch w.thread.specs[i].hookID {
+	case whKeyboardLl:
+		s := KbdllHookStruct{VkCode: uint32(watchdogProbeKey), DwExtraInfo: extraInfo}
+		return w.isProbe(i, uintptr(unsafe.Pointer(&s)))
+	default:
+		s := MsllHookStruct{DwExtraInfo: extraInfo}
+		return w.isProbe(i, uintptr(unsafe.Pointer(&s)))
+	}
+}
+
+// watchdogEvents returns the incidents that the watchdog has reported.
+func watchdogEvents(w *watchdog) []WatchdogEvent {
+	var events []WatchdogEvent
+
+	for {
+		select {
+		case event := <-w.events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestWatchdogTokens(t *testing.T) {
+	a := newTestWatchdog(&fakeBackend{})
+	b := newTestWatchdog(&fakeBackend{})
+
+	if a.token == b.token {
+		t.Fatalf("expected unique tokens, got %#x twice", a.token)
+	}
+
+	for _, w := range []*watchdog{a, b} {
+		if w.token&^0xFFFF != watchdogProbeBase {
+			t.Fatalf("token %#x is not based on %#x", w.token, watchdogProbeBase)
+		}
+	}
+
+	for i := range a.thread.specs {
+		if deliverProbe(a, i, b.token) {
+			t.Fatalf("hook %d: expected another watchdog's probe not to be consumed", i)
+		}
+
+		if deliverProbe(a, i, 0) {
+			t.Fatalf("hook %d: expected an untagged event not to be consumed", i)
+		}
+
+		if !deliverProbe(a, i, a.token) {
+			t.Fatalf("hook %d: expected the watchdog's probe to be consumed", i)
+		}
+
+		select {
+		case <-a.arrived[i]:
+		default:
+			t.Fatalf("hook %d: expected the probe's arrival to be signalled", i)
+		}
+	}
+}
+
+func TestWatchdogProbeArrives(t *testing.T) {
+	var w *watchdog
+
+	backend := &fakeBackend{
+		onSend: func(batch []Input) {
+			if len(batch) == 1 {
+				deliverProbe(w, 0, w.token)
+			}
+		},
+	}
+
+	w = newTestWatchdog(backend)
+
+	if !w.probe(0) {
+		t.Fatal("expected probe to return true")
+	}
+
+	probe := KeyUpInput(watchdogProbeKey)
+	probe.DwExtraInfo = w.token
+
+	exp := inputValues([]Input{NewKeybdInput(probe)})
+	got := inputValues(backend.inputs())
+	if len(got) != 1 || got[0] != exp[0] {
+		t.Fatalf("sent %+v, expected %+v", got, exp)
+	}
+
+	if events := watchdogEvents(w); len(events) > 0 {
+		t.Fatalf("expected no incidents, got %+v", events)
+	}
+}
+
+func TestWatchdogProbeTimeoutRequestsReinstall(t *testing.T) {
+	w := newTestWatchdog(&fakeBackend{})
+
+	// A probe that arrived after a previous timeout must not satisfy
+	// the next probe.
+	w.arrived[1] <- struct{}{}
+
+	if !w.probe(1) {
+		t.Fatal("expected probe to return true")
+	}
+
+	events := watchdogEvents(w)
+	if len(events) != 2 {
+		t.Fatalf("got incidents %+v, expected a timeout and a failed reinstall", events)
+	}
+
+	if events[0].Kind != WatchdogProbeTimeout || events[0].Hook != MouseEvents {
+		t.Fatalf("got incident %+v, expected a mouse probe timeout", events[0])
+	}
+
+	if events[0].Latency < w.config.ProbeTimeout {
+		t.Fatalf("got latency %s, expected at least %s", events[0].Latency, w.config.ProbeTimeout)
+	}
+
+	// PostThreadMessageW is unavailable, so the reinstall cannot
+	// be requested.
+	if events[1].Kind != WatchdogReinstallFailed || events[1].Hook != MouseEvents || events[1].Err == nil {
+		t.Fatalf("got incident %+v, expected a failed mouse reinstall", events[1])
+	}
+}
+
+func TestWatchdogProbeSendFailure(t *testing.T) {
+	sendErr := errors.New("send failed")
+	w := newTestWatchdog(&fakeBackend{sendErr: sendErr})
+
+	if !w.probe(0) {
+		t.Fatal("expected probe to return true")
+	}
+
+	events := watchdogEvents(w)
+	if len(events) != 1 {
+		t.Fatalf("got incidents %+v, expected a single failed probe", events)
+	}
+
+	if events[0].Kind != WatchdogProbeFailed || events[0].Hook != KeyboardEvents || !errors.Is(events[0].Err, sendErr) {
+		t.Fatalf("got incident %+v, expected a failed keyboard probe", events[0])
+	}
+}
+
+func TestWatchdogReinstalled(t *testing.T) {
+	w := newTestWatchdog(&fakeBackend{})
+	reinstallErr := errors.New("reinstall failed")
+
+	w.reinstalled(0, nil)
+	w.reinstalled(1, reinstallErr)
+
+	events := watchdogEvents(w)
+	if len(events) != 2 {
+		t.Fatalf("got incidents %+v, expected 2", events)
+	}
+
+	if events[0].Kind != WatchdogHookReinstalled || events[0].Hook != KeyboardEvents || events[0].Err != nil {
+		t.Fatalf("got incident %+v, expected a reinstalled keyboard hook", events[0])
+	}
+
+	if events[1].Kind != WatchdogReinstallFailed || events[1].Hook != MouseEvents || events[1].Err != reinstallErr {
+		t.Fatalf("got incident %+v, expected a failed mouse reinstall", events[1])
+	}
+
+	for _, event := range events {
+		if event.Time.IsZero() {
+			t.Fatalf("incident %+v has no time", event)
+		}
+	}
+}
+
+func TestWatchdogStop(t *testing.T) {
+	w := newTestWatchdog(&fakeBackend{})
+	w.config.InjectProbes = false
+
+	w.start()
+	w.stop()
+	w.stop()
+
+	// Incidents reported after the watchdog stops are discarded.
+	w.reinstalled(0, nil)
+
+	if _, ok := <-w.events; ok {
+		t.Fatal("expected the events channel to be closed")
+	}
+}